@@ -2,24 +2,42 @@ package main
 
 import (
 	"database/sql"
-	"encoding/json"
-	"fmt"
-	"log"
+	"errors"
 	"net/http"
+	"net/netip"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/marekbrze/chirpy/internal/auth"
 	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/fixtures"
+	"github.com/marekbrze/chirpy/internal/health"
+	"github.com/marekbrze/chirpy/internal/profanity"
+	"github.com/marekbrze/chirpy/internal/ratelimit"
+	"github.com/marekbrze/chirpy/internal/validate"
 )
 
 type apiConfig struct {
-	fileserverhits atomic.Int32
-	dbQueries      *database.Queries
-	platform       string
-	jwtSecret      string
-	apiKey         string
+	fileserverhits             atomic.Int32
+	draining                   atomic.Bool
+	dbQueries                  database.Querier
+	tx                         database.Transactor
+	platform                   string
+	jwtSecret                  string
+	apiKey                     string
+	accessTokenTTL             time.Duration
+	refreshTokenTTL            time.Duration
+	chirpRestoreGracePeriod    time.Duration
+	chirpRetention             time.Duration
+	accountDeletionGracePeriod time.Duration
+	profanity                  *profanity.Filter
+	rateLimiter                ratelimit.Store
+	trustedProxies             []netip.Prefix
+	readiness                  *health.Checker
+	metrics                    *metrics
+	fixtures                   fixtures.Dir
+	snapshots                  *fixtures.Snapshots
 }
 
 type UserData struct {
@@ -27,12 +45,46 @@ type UserData struct {
 	Password string `json:"password"`
 }
 
+func (u UserData) validate(v *validate.Validator) {
+	v.Check(validate.NotBlank(u.Email), "email", "is required")
+	v.Check(validate.Email(u.Email), "email", "must be an email address")
+	v.Check(validate.NotBlank(u.Password), "password", "is required")
+}
+
 type User struct {
 	ID          uuid.UUID `json:"id"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Email       string    `json:"email"`
 	IsChirpyRed bool      `json:"is_chirpy_red"`
+	Handle      string    `json:"handle,omitempty"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+}
+
+func userFromDB(user database.User) User {
+	return User{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		IsChirpyRed: user.IsChirpyRed,
+		Handle:      user.Handle.String,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+	}
+}
+
+// loginData carries new_password only when an admin has required a password
+// reset. The new password replaces the old one as part of logging in.
+type loginData struct {
+	UserData
+	NewPassword string `json:"new_password"`
+}
+
+func (d loginData) validate(v *validate.Validator) {
+	d.UserData.validate(v)
+	v.Check(d.NewPassword == "" || d.NewPassword != d.Password, "new_password", "must be different from the current password")
 }
 
 type TokenResponse struct {
@@ -48,52 +100,21 @@ type SimpleTokenResponse struct {
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cfg.fileserverhits.Add(1)
+		cfg.metrics.fileserverHits.Inc()
 		next.ServeHTTP(w, r)
 	})
 }
 
-func (cfg *apiConfig) resetMetrics(w http.ResponseWriter, r *http.Request) {
-	cfg.fileserverhits.Store(0)
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	responseBody := []byte("Hits reseted")
-
-	_, err := w.Write(responseBody)
-	if err != nil {
-		// Log any error that occurs during writing the response.
-		log.Println("Failed to write response:", err)
-	}
-}
-
-func (cfg *apiConfig) getNumberOfHits(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	responseString := fmt.Sprintf(`<html>
-  <body>
-    <h1>Welcome, Chirpy Admin</h1>
-    <p>Chirpy has been visited %d times!</p>
-  </body>
-</html>`, cfg.fileserverhits.Load())
-	responseBody := []byte(responseString)
-
-	_, err := w.Write(responseBody)
-	if err != nil {
-		// Log any error that occurs during writing the response.
-		log.Println("Failed to write response:", err)
-	}
-}
-
 func (cfg *apiConfig) addUser(w http.ResponseWriter, r *http.Request) {
-	decoder := json.NewDecoder(r.Body)
 	receivedUserData := UserData{}
-	err := decoder.Decode(&receivedUserData)
+	err := decodeJSON(w, r, &receivedUserData)
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, r, err)
 		return
 	}
 	hashedPassword, err := auth.HashPassword(receivedUserData.Password)
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, r, err)
 		return
 	}
 	userParams := database.CreateUserParams{
@@ -105,99 +126,143 @@ func (cfg *apiConfig) addUser(w http.ResponseWriter, r *http.Request) {
 	}
 	user, err := cfg.dbQueries.CreateUser(r.Context(), userParams)
 	if err != nil {
-		log.Println("Failed to write response:", err)
-	}
-	responseUser := User{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       user.Email,
-		IsChirpyRed: user.IsChirpyRed,
+		if database.IsUniqueViolation(err) {
+			respondWithError(w, r, errEmailTaken)
+			return
+		}
+		respondWithError(w, r, err)
+		return
 	}
-	respondWithJSON(w, 201, responseUser)
+	respondWithJSON(w, 201, userFromDB(user))
 }
 
 func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
-	decoder := json.NewDecoder(r.Body)
-	receivedUserData := UserData{}
-	err := decoder.Decode(&receivedUserData)
+	receivedUserData := loginData{}
+	err := decodeJSON(w, r, &receivedUserData)
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
-		return
-	}
-	user, err := cfg.dbQueries.GetUser(r.Context(), receivedUserData.Email)
-	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
-		return
-	}
-	loginCorrect, err := auth.CheckPasswordHash(receivedUserData.Password, user.HashedPassword)
-	if err != nil || !loginCorrect {
-		respondWithError(w, 401, "Incorrect email or password")
+		respondWithError(w, r, err)
 		return
 	}
-	expiresIn := time.Hour
-	token, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Duration(expiresIn))
-	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+	newHash := ""
+	if receivedUserData.NewPassword != "" {
+		newHash, err = auth.HashPassword(receivedUserData.NewPassword)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
 	}
 	refreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, r, err)
+		return
 	}
-	refreshTokenParams := database.CreateRefreshTokenParams{
-		Token:     refreshToken,
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-		ExpiresAt: time.Now().AddDate(0, 0, 60).UTC(),
-		UserID:    user.ID,
+	var user database.User
+	var savedToken database.RefreshToken
+	err = cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		var err error
+		user, err = q.GetUser(r.Context(), receivedUserData.Email)
+		if err == sql.ErrNoRows {
+			return errIncorrectLogin
+		}
+		if err != nil {
+			return err
+		}
+		loginCorrect, err := auth.CheckPasswordHash(receivedUserData.Password, user.HashedPassword)
+		if err != nil || !loginCorrect {
+			return errIncorrectLogin
+		}
+		if user.SuspendedAt.Valid {
+			return errAccountSuspended
+		}
+		if user.PasswordResetRequired {
+			if newHash == "" {
+				return errPasswordResetRequired
+			}
+			user, err = q.UpdateUser(r.Context(), database.UpdateUserParams{
+				ID:             user.ID,
+				Email:          user.Email,
+				HashedPassword: newHash,
+				UpdatedAt:      time.Now().UTC(),
+			})
+			if err != nil {
+				return err
+			}
+			if err := audit(r.Context(), q, r, auditPasswordChange, user.ID, user.ID, map[string]any{"reset_required": true}); err != nil {
+				return err
+			}
+		}
+		if user.DeletedAt.Valid {
+			// Logging in during the grace period cancels the deletion.
+			user, err = q.SetUserDeleted(r.Context(), database.SetUserDeletedParams{
+				UpdatedAt: time.Now().UTC(),
+				ID:        user.ID,
+			})
+			if err != nil {
+				return err
+			}
+			if err := audit(r.Context(), q, r, auditDeletionCancel, user.ID, user.ID, nil); err != nil {
+				return err
+			}
+		}
+		savedToken, err = q.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+			Token:     refreshToken,
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			ExpiresAt: time.Now().Add(cfg.refreshTokenTTL).UTC(),
+			UserID:    user.ID,
+		})
+		if err != nil {
+			return err
+		}
+		return audit(r.Context(), q, r, auditLogin, user.ID, user.ID, nil)
+	})
+	if err != nil {
+		if errors.Is(err, errAccountSuspended) {
+			cfg.metrics.logins.WithLabelValues("suspended").Inc()
+		} else {
+			cfg.metrics.logins.WithLabelValues("failure").Inc()
+		}
+		cfg.auditFailedLogin(r, receivedUserData.Email, user.ID, err)
+		respondWithError(w, r, err)
+		return
 	}
-	savedToken, err := cfg.dbQueries.CreateRefreshToken(r.Context(), refreshTokenParams)
+	token, err := auth.MakeJWT(user.ID, cfg.jwtSecret, cfg.accessTokenTTL)
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, r, err)
+		return
 	}
 	responseWithToken := TokenResponse{
-		User: User{
-			ID:          user.ID,
-			CreatedAt:   user.CreatedAt,
-			UpdatedAt:   user.UpdatedAt,
-			Email:       user.Email,
-			IsChirpyRed: user.IsChirpyRed,
-		},
+		User:         userFromDB(user),
 		Token:        token,
 		RefreshToken: savedToken.Token,
 	}
+	cfg.metrics.logins.WithLabelValues("success").Inc()
 	respondWithJSON(w, 200, responseWithToken)
 }
 
-func (cfg *apiConfig) reset(w http.ResponseWriter, r *http.Request) {
-	if cfg.platform != "dev" {
-		respondWithJSON(w, 403, nil)
-	}
-	err := cfg.dbQueries.DeleteUsers(r.Context())
-	if err != nil {
-		log.Println("Failed to write response:", err)
-	}
-	respondWithJSON(w, 200, nil)
-}
-
 func (cfg *apiConfig) refreshToken(w http.ResponseWriter, r *http.Request) {
 	headerToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+		respondWithError(w, r, errUnauthorized)
 		return
 	}
 	tokenInfo, err := cfg.dbQueries.GetTokenInfo(r.Context(), headerToken)
 	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+		respondWithError(w, r, errUnauthorized)
 		return
 	}
-	if tokenInfo.ExpiresAt.Before(time.Now()) || tokenInfo.RevokedAt.Valid {
-		respondWithError(w, 401, "Unauthorized")
+	if tokenInfo.ExpiresAt.Before(time.Now()) || tokenInfo.RevokedAt.Valid || tokenInfo.SuspendedAt.Valid {
+		respondWithError(w, r, errUnauthorized)
+		return
+	}
+	newToken, err := auth.MakeJWT(tokenInfo.UserID, cfg.jwtSecret, cfg.accessTokenTTL)
+	if err != nil {
+		respondWithError(w, r, errUnauthorized)
 		return
 	}
-	newToken, err := auth.MakeJWT(tokenInfo.UserID, cfg.jwtSecret, time.Hour)
+	err = audit(r.Context(), cfg.dbQueries, r, auditTokenRefresh, tokenInfo.UserID, tokenInfo.UserID, nil)
 	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+		respondWithError(w, r, err)
 		return
 	}
 	responseWithToken := SimpleTokenResponse{
@@ -209,16 +274,20 @@ func (cfg *apiConfig) refreshToken(w http.ResponseWriter, r *http.Request) {
 func (cfg *apiConfig) revokeToken(w http.ResponseWriter, r *http.Request) {
 	headerToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+		respondWithError(w, r, errUnauthorized)
 		return
 	}
 	tokenInfo, err := cfg.dbQueries.GetTokenInfo(r.Context(), headerToken)
+	if err == sql.ErrNoRows {
+		respondWithError(w, r, errUnauthorized)
+		return
+	}
 	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+		respondWithError(w, r, err)
 		return
 	}
 	if tokenInfo.ExpiresAt.Before(time.Now()) || tokenInfo.RevokedAt.Valid {
-		respondWithError(w, 401, "Unauthorized")
+		respondWithError(w, r, errUnauthorized)
 		return
 	}
 	revokeTokenParams := database.RevokeTokenParams{
@@ -229,10 +298,15 @@ func (cfg *apiConfig) revokeToken(w http.ResponseWriter, r *http.Request) {
 		},
 		Token: tokenInfo.Token,
 	}
-	err = cfg.dbQueries.RevokeToken(r.Context(), revokeTokenParams)
+	err = cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		if err := q.RevokeToken(r.Context(), revokeTokenParams); err != nil {
+			return err
+		}
+		return audit(r.Context(), q, r, auditTokenRevoke, tokenInfo.UserID, tokenInfo.UserID, nil)
+	})
 	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+		respondWithError(w, r, err)
 		return
 	}
-	respondWithError(w, 204, "Token revoked")
+	w.WriteHeader(204)
 }
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/fixtures"
+	"github.com/marekbrze/chirpy/internal/problem"
+)
+
+// The fixture API lets test suites put the database in a known state. It
+// has no authentication of its own, so every handler checks the platform
+// first. Audit events, rate limits and banned words are left alone: the
+// audit log is append-only, and the others aren't test data.
+
+type fixtureList struct {
+	Fixtures []string `json:"fixtures"`
+}
+
+// requireTestPlatform responds with 403 and returns false on platforms
+// where the fixture API must not be used.
+func (cfg *apiConfig) requireTestPlatform(w http.ResponseWriter, r *http.Request) bool {
+	if cfg.platform != "dev" && cfg.platform != "test" {
+		respondWithError(w, r, problem.New(problem.Forbidden, "Only available on the dev and test platforms"))
+		return false
+	}
+	return true
+}
+
+// reset deletes every user, and with them everything users own, then loads
+// the fixture set named in the fixture query parameter, if there is one.
+func (cfg *apiConfig) reset(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireTestPlatform(w, r) {
+		return
+	}
+	set := fixtures.Set{}
+	if name := r.URL.Query().Get("fixture"); name != "" {
+		var err error
+		set, err = cfg.loadFixture(name)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+	}
+	err := cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		if err := q.DeleteUsers(r.Context()); err != nil {
+			return err
+		}
+		return fixtures.Load(r.Context(), q, set)
+	})
+	if err != nil {
+		respondWithError(w, r, fixtureError(err))
+		return
+	}
+	cfg.fileserverhits.Store(0)
+	respondWithJSON(w, 200, nil)
+}
+
+func (cfg *apiConfig) listFixtures(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireTestPlatform(w, r) {
+		return
+	}
+	names, err := cfg.fixtures.Names()
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, fixtureList{Fixtures: names})
+}
+
+// applyFixture loads a set on top of whatever is already there.
+func (cfg *apiConfig) applyFixture(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireTestPlatform(w, r) {
+		return
+	}
+	set, err := cfg.loadFixture(r.PathValue("name"))
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	err = cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		return fixtures.Load(r.Context(), q, set)
+	})
+	if err != nil {
+		respondWithError(w, r, fixtureError(err))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) takeSnapshot(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireTestPlatform(w, r) {
+		return
+	}
+	name := r.PathValue("name")
+	if err := fixtures.CheckName(name); err != nil {
+		respondWithError(w, r, fixtureError(err))
+		return
+	}
+	var set fixtures.Set
+	err := cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		var err error
+		set, err = fixtures.Snapshot(r.Context(), q)
+		return err
+	})
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	cfg.snapshots.Save(name, set)
+	w.Header().Set("Location", "/admin/snapshots/"+name)
+	w.WriteHeader(201)
+}
+
+// getSnapshot responds with the snapshot as a fixture set, so it can be
+// saved to the fixtures directory and loaded by name later.
+func (cfg *apiConfig) getSnapshot(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireTestPlatform(w, r) {
+		return
+	}
+	set, err := cfg.snapshots.Get(r.PathValue("name"))
+	if err != nil {
+		respondWithError(w, r, snapshotError(err))
+		return
+	}
+	respondWithJSON(w, 200, set)
+}
+
+// restoreSnapshot replaces everything with the snapshot, like a reset that
+// loads it.
+func (cfg *apiConfig) restoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireTestPlatform(w, r) {
+		return
+	}
+	set, err := cfg.snapshots.Get(r.PathValue("name"))
+	if err != nil {
+		respondWithError(w, r, snapshotError(err))
+		return
+	}
+	err = cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		if err := q.DeleteUsers(r.Context()); err != nil {
+			return err
+		}
+		return fixtures.Load(r.Context(), q, set)
+	})
+	if err != nil {
+		respondWithError(w, r, fixtureError(err))
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// loadFixture reads the named set and prepares it, which hashes passwords,
+// before any transaction is started.
+func (cfg *apiConfig) loadFixture(name string) (fixtures.Set, error) {
+	set, err := cfg.fixtures.Load(name)
+	if err != nil {
+		return fixtures.Set{}, fixtureError(err)
+	}
+	if err := set.Prepare(time.Now().UTC()); err != nil {
+		return fixtures.Set{}, fixtureError(err)
+	}
+	return set, nil
+}
+
+func fixtureError(err error) error {
+	switch {
+	case errors.Is(err, fixtures.ErrNotFound):
+		return problem.New(problem.NotFound, "No fixture set with that name")
+	case errors.Is(err, fixtures.ErrInvalidName):
+		return problem.Wrap(problem.InvalidInput, err, "Names may only have lower case letters, digits, - and _")
+	case errors.Is(err, fixtures.ErrInvalid), errors.Is(err, fixtures.ErrUnknownUser):
+		return problem.Wrap(problem.InvalidInput, err, err.Error())
+	case database.IsForeignKeyViolation(err):
+		return problem.Wrap(problem.InvalidInput, err, "The fixture set refers to a chirp that doesn't exist")
+	case database.IsUniqueViolation(err):
+		return problem.Wrap(problem.Conflict, err, "The fixture set clashes with data that's already there")
+	}
+	return err
+}
+
+func snapshotError(err error) error {
+	if errors.Is(err, fixtures.ErrNotFound) {
+		return problem.New(problem.NotFound, "No snapshot with that name")
+	}
+	return err
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+// authenticateAdmin writes the error response itself, so handlers only need
+// to return when ok is false.
+func (cfg *apiConfig) authenticateAdmin(w http.ResponseWriter, r *http.Request) (admin database.User, ok bool) {
+	user, err := cfg.authenticateUser(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return database.User{}, false
+	}
+	if !user.IsAdmin {
+		respondWithError(w, r, errForbidden)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+func (cfg *apiConfig) getOpenReports(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.authenticateAdmin(w, r); !ok {
+		return
+	}
+	reports, err := cfg.dbQueries.GetOpenReports(r.Context())
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	responseReports := []report{}
+	for _, dbReport := range reports {
+		responseReports = append(responseReports, reportFromDB(dbReport))
+	}
+	respondWithJSON(w, 200, responseReports)
+}
+
+func (cfg *apiConfig) hideChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpHidden(w, r, true)
+}
+
+func (cfg *apiConfig) restoreHiddenChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpHidden(w, r, false)
+}
+
+func (cfg *apiConfig) setChirpHidden(w http.ResponseWriter, r *http.Request, hidden bool) {
+	admin, ok := cfg.authenticateAdmin(w, r)
+	if !ok {
+		return
+	}
+	chirpID, err := parseUUIDPathValue(r, "chirpID")
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	dbChirp, err := cfg.changeChirpHidden(r, admin, chirpID, hidden)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, r, errChirpNotFound)
+			return
+		}
+		respondWithError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, chirpFromDB(dbChirp))
+}
+
+// changeChirpHidden hides or restores the chirp as admin and records it.
+// Hiding a chirp also resolves its open reports.
+func (cfg *apiConfig) changeChirpHidden(r *http.Request, admin database.User, chirpID uuid.UUID, hidden bool) (database.Chirp, error) {
+	hiddenAt := sql.NullTime{}
+	if hidden {
+		hiddenAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	}
+	var dbChirp database.Chirp
+	err := cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		var err error
+		dbChirp, err = q.SetChirpHidden(r.Context(), database.SetChirpHiddenParams{
+			HiddenAt:  hiddenAt,
+			UpdatedAt: time.Now().UTC(),
+			ID:        chirpID,
+		})
+		if err != nil {
+			return err
+		}
+		action := auditRestoreChirp
+		if hidden {
+			action = auditHideChirp
+		}
+		err = audit(r.Context(), q, r, action, admin.ID, dbChirp.UserID, map[string]any{"chirp_id": dbChirp.ID})
+		if err != nil || !hidden {
+			return err
+		}
+		return q.ResolveChirpReports(r.Context(), database.ResolveChirpReportsParams{
+			Status:     "actioned",
+			ResolvedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			ResolvedBy: uuid.NullUUID{UUID: admin.ID, Valid: true},
+			UpdatedAt:  time.Now().UTC(),
+			ChirpID:    dbChirp.ID,
+		})
+	})
+	return dbChirp, err
+}
+
+func (cfg *apiConfig) dismissReport(w http.ResponseWriter, r *http.Request) {
+	admin, ok := cfg.authenticateAdmin(w, r)
+	if !ok {
+		return
+	}
+	reportID, err := parseUUIDPathValue(r, "reportID")
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	dbReport, err := cfg.dismissReportAs(r, admin, reportID)
+	if err != nil {
+		respondWithReportError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, reportFromDB(dbReport))
+}
+
+func (cfg *apiConfig) dismissReportAs(r *http.Request, admin database.User, reportID uuid.UUID) (database.Report, error) {
+	var dbReport database.Report
+	err := cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		var err error
+		dbReport, err = resolveReport(r.Context(), q, admin, reportID, "dismissed")
+		if err != nil {
+			return err
+		}
+		return audit(r.Context(), q, r, auditDismissReport, admin.ID, uuid.Nil, map[string]any{"report_id": dbReport.ID, "chirp_id": dbReport.ChirpID})
+	})
+	return dbReport, err
+}
+
+func (cfg *apiConfig) suspendReportedAuthor(w http.ResponseWriter, r *http.Request) {
+	admin, ok := cfg.authenticateAdmin(w, r)
+	if !ok {
+		return
+	}
+	reportID, err := parseUUIDPathValue(r, "reportID")
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	var dbReport database.Report
+	err = cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		var err error
+		dbReport, err = resolveReport(r.Context(), q, admin, reportID, "actioned")
+		if err != nil {
+			return err
+		}
+		dbChirp, err := q.GetChirpIncludingDeleted(r.Context(), dbReport.ChirpID)
+		if err != nil {
+			return err
+		}
+		if err := suspendUser(r.Context(), q, dbChirp.UserID); err != nil {
+			return err
+		}
+		return audit(r.Context(), q, r, auditSuspendUser, admin.ID, dbChirp.UserID, map[string]any{"report_id": dbReport.ID, "chirp_id": dbChirp.ID})
+	})
+	if err != nil {
+		respondWithReportError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, reportFromDB(dbReport))
+}
+
+// resolveReport fails with errReportResolved when the report isn't open any
+// more and with sql.ErrNoRows when there's no such report.
+func resolveReport(ctx context.Context, q database.Querier, admin database.User, reportID uuid.UUID, status string) (database.Report, error) {
+	dbReport, err := q.ResolveReport(ctx, database.ResolveReportParams{
+		Status:     status,
+		ResolvedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		ResolvedBy: uuid.NullUUID{UUID: admin.ID, Valid: true},
+		UpdatedAt:  time.Now().UTC(),
+		ID:         reportID,
+	})
+	if err != sql.ErrNoRows {
+		return dbReport, err
+	}
+	if _, err := q.GetReport(ctx, reportID); err != nil {
+		return database.Report{}, err
+	}
+	return database.Report{}, errReportResolved
+}
+
+func respondWithReportError(w http.ResponseWriter, r *http.Request, err error) {
+	if err == sql.ErrNoRows {
+		respondWithError(w, r, errReportNotFound)
+		return
+	}
+	respondWithError(w, r, err)
+}
+
+// suspendUser blocks the user from logging in and revokes every refresh
+// token. It takes effect at once, since authenticateUser rejects suspended
+// accounts on every request, even with a JWT that hasn't expired.
+func suspendUser(ctx context.Context, q database.Querier, userID uuid.UUID) error {
+	_, err := q.SetUserSuspended(ctx, database.SetUserSuspendedParams{
+		SuspendedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		UpdatedAt:   time.Now().UTC(),
+		ID:          userID,
+	})
+	if err != nil {
+		return err
+	}
+	return q.RevokeUserTokens(ctx, database.RevokeUserTokensParams{
+		RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		UpdatedAt: time.Now().UTC(),
+		UserID:    userID,
+	})
+}
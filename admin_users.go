@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/validate"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// userDetails is a user as admins see it, including the account state that's
+// hidden from the public API.
+type userDetails struct {
+	User
+	IsAdmin               bool       `json:"is_admin"`
+	SuspendedAt           *time.Time `json:"suspended_at,omitempty"`
+	DeletedAt             *time.Time `json:"deleted_at,omitempty"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+}
+
+func userDetailsFromDB(user database.User) userDetails {
+	return userDetails{
+		User:                  userFromDB(user),
+		IsAdmin:               user.IsAdmin,
+		SuspendedAt:           nullTime(user.SuspendedAt),
+		DeletedAt:             nullTime(user.DeletedAt),
+		PasswordResetRequired: user.PasswordResetRequired,
+	}
+}
+
+type userPage struct {
+	Users      []userDetails `json:"users"`
+	NextOffset *int          `json:"next_offset,omitempty"`
+}
+
+func (cfg *apiConfig) listUsers(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.authenticateAdmin(w, r); !ok {
+		return
+	}
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	// One extra row tells us whether there's another page.
+	dbUsers, err := cfg.dbQueries.SearchUsers(r.Context(), database.SearchUsersParams{
+		Query:  escapeLike(r.URL.Query().Get("email")),
+		Limit:  int32(limit + 1),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	page := userPage{Users: []userDetails{}}
+	if len(dbUsers) > limit {
+		dbUsers = dbUsers[:limit]
+		next := offset + limit
+		page.NextOffset = &next
+	}
+	for _, dbUser := range dbUsers {
+		page.Users = append(page.Users, userDetailsFromDB(dbUser))
+	}
+	respondWithJSON(w, 200, page)
+}
+
+// parsePage reads the limit and offset query parameters.
+func parsePage(r *http.Request) (limit, offset int, err error) {
+	v := validate.Validator{}
+	limit = defaultPageSize
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		v.Check(err == nil && limit >= 1 && limit <= maxPageSize, "limit", "must be a number from 1 to 100")
+	}
+	if s := r.URL.Query().Get("offset"); s != "" {
+		offset, err = strconv.Atoi(s)
+		v.Check(err == nil && offset >= 0, "offset", "must be a number that isn't negative")
+	}
+	return limit, offset, v.Err()
+}
+
+// escapeLike stops % and _ in a search from acting as wildcards.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+type userWithAudit struct {
+	userDetails
+	AuditEvents []auditEvent `json:"audit_events"`
+}
+
+func (cfg *apiConfig) getUserDetails(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.authenticateAdmin(w, r); !ok {
+		return
+	}
+	userID, err := parseUUIDPathValue(r, "userID")
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, r, errUserNotFound)
+			return
+		}
+		respondWithError(w, r, err)
+		return
+	}
+	dbEvents, err := cfg.dbQueries.GetAuditEventsByTarget(r.Context(), database.GetAuditEventsByTargetParams{
+		TargetID: uuid.NullUUID{UUID: userID, Valid: true},
+		Limit:    defaultPageSize,
+	})
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	response := userWithAudit{userDetails: userDetailsFromDB(dbUser), AuditEvents: []auditEvent{}}
+	for _, dbEvent := range dbEvents {
+		response.AuditEvents = append(response.AuditEvents, auditEventFromDB(dbEvent))
+	}
+	respondWithJSON(w, 200, response)
+}
+
+func (cfg *apiConfig) suspendUserByAdmin(w http.ResponseWriter, r *http.Request) {
+	cfg.changeUser(w, r, auditSuspendUser, func(ctx context.Context, q database.Querier, userID uuid.UUID) (database.User, error) {
+		if err := suspendUser(ctx, q, userID); err != nil {
+			return database.User{}, err
+		}
+		return q.GetUserByID(ctx, userID)
+	})
+}
+
+func (cfg *apiConfig) unsuspendUser(w http.ResponseWriter, r *http.Request) {
+	cfg.changeUser(w, r, auditUnsuspendUser, func(ctx context.Context, q database.Querier, userID uuid.UUID) (database.User, error) {
+		return q.SetUserSuspended(ctx, database.SetUserSuspendedParams{
+			UpdatedAt: time.Now().UTC(),
+			ID:        userID,
+		})
+	})
+}
+
+// requirePasswordReset logs the user out everywhere. They can only log in
+// again by choosing a new password, see loginUser.
+func (cfg *apiConfig) requirePasswordReset(w http.ResponseWriter, r *http.Request) {
+	cfg.changeUser(w, r, auditRequirePasswordReset, func(ctx context.Context, q database.Querier, userID uuid.UUID) (database.User, error) {
+		user, err := q.SetPasswordResetRequired(ctx, database.SetPasswordResetRequiredParams{
+			PasswordResetRequired: true,
+			UpdatedAt:             time.Now().UTC(),
+			ID:                    userID,
+		})
+		if err != nil {
+			return database.User{}, err
+		}
+		return user, q.RevokeUserTokens(ctx, database.RevokeUserTokensParams{
+			RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			UpdatedAt: time.Now().UTC(),
+			UserID:    userID,
+		})
+	})
+}
+
+func (cfg *apiConfig) grantChirpyRed(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpyRed(w, r, true)
+}
+
+func (cfg *apiConfig) revokeChirpyRed(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpyRed(w, r, false)
+}
+
+func (cfg *apiConfig) setChirpyRed(w http.ResponseWriter, r *http.Request, isChirpyRed bool) {
+	action := auditRevokeChirpyRed
+	if isChirpyRed {
+		action = auditGrantChirpyRed
+	}
+	cfg.changeUser(w, r, action, func(ctx context.Context, q database.Querier, userID uuid.UUID) (database.User, error) {
+		return q.UpgradeUser(ctx, database.UpgradeUserParams{
+			IsChirpyRed: isChirpyRed,
+			UpdatedAt:   time.Now().UTC(),
+			ID:          userID,
+		})
+	})
+}
+
+// changeUser applies change to the user in the path and records action in
+// the same transaction, then responds with the updated user.
+func (cfg *apiConfig) changeUser(w http.ResponseWriter, r *http.Request, action string, change func(ctx context.Context, q database.Querier, userID uuid.UUID) (database.User, error)) {
+	admin, ok := cfg.authenticateAdmin(w, r)
+	if !ok {
+		return
+	}
+	userID, err := parseUUIDPathValue(r, "userID")
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	var user database.User
+	err = cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		var err error
+		user, err = change(r.Context(), q, userID)
+		if err != nil {
+			return err
+		}
+		return audit(r.Context(), q, r, action, admin.ID, userID, nil)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, r, errUserNotFound)
+			return
+		}
+		respondWithError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, userDetailsFromDB(user))
+}
@@ -2,87 +2,105 @@ package main
 
 import (
 	"database/sql"
-	"encoding/json"
-	"log"
 	"net/http"
 	"sort"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/marekbrze/chirpy/internal/auth"
 	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/problem"
+	"github.com/marekbrze/chirpy/internal/validate"
 )
 
 type receivedChirp struct {
 	Body string `json:"body"`
 }
 
+func (c receivedChirp) validate(v *validate.Validator) {
+	v.Check(validate.NotBlank(c.Body), "body", "is required")
+	v.Check(validate.MaxLength(c.Body, 140), "body", "must be at most 140 characters")
+}
+
 type chirp struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Body      string    `json:"body"`
 	UserID    uuid.UUID `json:"user_id"`
+	Author    *author   `json:"author,omitempty"`
 }
 
-func healthCheck(writer http.ResponseWriter, request *http.Request) {
+func (cfg *apiConfig) healthCheck(writer http.ResponseWriter, request *http.Request) {
+	status := http.StatusOK
+	if cfg.draining.Load() {
+		// Tell the load balancer to stop sending traffic before we shut down.
+		status = http.StatusServiceUnavailable
+	}
 	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	writer.WriteHeader(http.StatusOK)
-	responseBody := []byte(http.StatusText(http.StatusOK))
+	writer.WriteHeader(status)
+	responseBody := []byte(http.StatusText(status))
 
 	_, err := writer.Write(responseBody)
 	if err != nil {
 		// Log any error that occurs during writing the response.
-		log.Println("Failed to write response:", err)
+		loggerFrom(request.Context()).Error("Failed to write response", "error", err)
 	}
 }
 
 func (cfg *apiConfig) addChirp(w http.ResponseWriter, r *http.Request) {
-	headerToken, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
-		return
-	}
-	userID, err := auth.ValidateJWT(headerToken, cfg.jwtSecret)
+	userID, err := cfg.authenticate(r)
 	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+		respondWithError(w, r, err)
 		return
 	}
-	decoder := json.NewDecoder(r.Body)
 	receivedChirp := receivedChirp{}
-	err = decoder.Decode(&receivedChirp)
+	err = decodeJSON(w, r, &receivedChirp)
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, r, err)
 		return
 	}
-	if len(receivedChirp.Body) > 140 {
-		respondWithError(w, 400, "Chirp is too long")
+	checkedChirp := cfg.profanity.Check(receivedChirp.Body)
+	if checkedChirp.Rejected {
+		respondWithError(w, r, problem.Invalid(problem.FieldError{Field: "body", Message: "contains banned words"}))
 		return
 	}
-	cleanedChirp := eraseProfane(receivedChirp.Body)
+	if checkedChirp.Flagged {
+		loggerFrom(r.Context()).Warn("Chirp flagged for review", "matches", checkedChirp.Matches)
+	}
 	chirpParams := database.CreateChirpParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
-		Body:      cleanedChirp,
+		Body:      checkedChirp.Text,
 		UserID:    userID,
 	}
 	savedChirp, err := cfg.dbQueries.CreateChirp(r.Context(), chirpParams)
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, r, err)
 		return
 	}
-	responseChirp := chirp{
-		ID:        savedChirp.ID,
-		CreatedAt: savedChirp.CreatedAt,
-		UpdatedAt: savedChirp.UpdatedAt,
-		Body:      savedChirp.Body,
-		UserID:    savedChirp.UserID,
+	cfg.metrics.chirpsCreated.Inc()
+	responseChirps := []chirp{chirpFromDB(savedChirp)}
+	if err := cfg.addAuthors(r.Context(), responseChirps); err != nil {
+		respondWithError(w, r, err)
+		return
 	}
-	respondWithJSON(w, 201, responseChirp)
+	respondWithJSON(w, 201, responseChirps[0])
 }
 
 func (cfg *apiConfig) getAllChirps(w http.ResponseWriter, r *http.Request) {
+	viewerInfo := uuid.NullUUID{}
+	if r.Header.Get("Authorization") != "" {
+		viewerID, err := cfg.authenticate(r)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+		viewerInfo = uuid.NullUUID{
+			UUID:  viewerID,
+			Valid: true,
+		}
+	}
 	authorInfo := uuid.NullUUID{}
 	s := r.URL.Query().Get("author_id")
 	if s != "" {
@@ -92,26 +110,26 @@ func (cfg *apiConfig) getAllChirps(w http.ResponseWriter, r *http.Request) {
 			Valid: true,
 		}
 		if err != nil {
-			respondWithError(w, 500, "Something went wrong")
+			respondWithError(w, r, problem.Invalid(problem.FieldError{Field: "author_id", Message: "must be a UUID"}))
 			return
 		}
 	}
-	chirps, err := cfg.dbQueries.GetAllChirps(r.Context(), authorInfo)
+	chirps, err := cfg.dbQueries.GetAllChirps(r.Context(), database.GetAllChirpsParams{
+		ViewerID: viewerInfo,
+		UserID:   authorInfo,
+	})
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, r, err)
 		return
 	}
 	var responseChirps []chirp
 	for _, dbChirp := range chirps {
-		responseChirp := chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    dbChirp.UserID,
-		}
+		responseChirps = append(responseChirps, chirpFromDB(dbChirp))
+	}
 
-		responseChirps = append(responseChirps, responseChirp)
+	if err := cfg.addAuthors(r.Context(), responseChirps); err != nil {
+		respondWithError(w, r, err)
+		return
 	}
 
 	s = r.URL.Query().Get("sort")
@@ -124,26 +142,38 @@ func (cfg *apiConfig) getAllChirps(w http.ResponseWriter, r *http.Request) {
 }
 
 func (cfg *apiConfig) getChirpByID(w http.ResponseWriter, r *http.Request) {
-	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	chirpID, err := parseUUIDPathValue(r, "chirpID")
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, r, err)
 		return
 	}
 	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			respondWithError(w, 404, "Chirp doesn't exist")
+			respondWithError(w, r, errChirpNotFound)
 			return
 		}
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, r, err)
 		return
 	}
-	responseChirp := chirp{
+	if dbChirp.HiddenAt.Valid {
+		respondWithError(w, r, errChirpNotFound)
+		return
+	}
+	responseChirps := []chirp{chirpFromDB(dbChirp)}
+	if err := cfg.addAuthors(r.Context(), responseChirps); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, responseChirps[0])
+}
+
+func chirpFromDB(dbChirp database.Chirp) chirp {
+	return chirp{
 		ID:        dbChirp.ID,
 		CreatedAt: dbChirp.CreatedAt,
 		UpdatedAt: dbChirp.UpdatedAt,
 		Body:      dbChirp.Body,
 		UserID:    dbChirp.UserID,
 	}
-	respondWithJSON(w, 200, responseChirp)
 }
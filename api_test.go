@@ -0,0 +1,833 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/config"
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/fixtures"
+	"github.com/marekbrze/chirpy/internal/memdb"
+	"github.com/marekbrze/chirpy/internal/problem"
+	"github.com/marekbrze/chirpy/internal/profanity"
+	"github.com/marekbrze/chirpy/internal/ratelimit"
+)
+
+const testFixture = `
+users:
+  - email: skyler@example.com
+    password: hunter2
+    handle: skyler
+chirps:
+  - author: skyler@example.com
+    body: Someone has to protect this family from the man who protects this family.
+`
+
+func newTestAPI(t *testing.T) http.Handler {
+	t.Helper()
+	api, _ := newTestAPIWithDB(t)
+	return api
+}
+
+// newTestAPIWithDB also returns the database, for setting up what the API
+// can't, like admins.
+func newTestAPIWithDB(t *testing.T) (http.Handler, *memdb.DB) {
+	t.Helper()
+	db := memdb.New()
+	cfg := &apiConfig{
+		dbQueries:                  db,
+		tx:                         db,
+		platform:                   "dev",
+		jwtSecret:                  "test-secret",
+		apiKey:                     "test-polka-key",
+		accessTokenTTL:             time.Hour,
+		refreshTokenTTL:            time.Hour,
+		chirpRestoreGracePeriod:    time.Hour,
+		chirpRetention:             2 * time.Hour,
+		accountDeletionGracePeriod: time.Hour,
+		profanity:                  profanity.New(profanity.StaticSource(profanity.DefaultRules)),
+		rateLimiter:                ratelimit.NewMemoryStore(),
+		metrics:                    newMetrics(nil),
+		fixtures:                   fixtures.Dir{FS: fstest.MapFS{"cast.yaml": {Data: []byte(testFixture)}}},
+		snapshots:                  fixtures.NewSnapshots(),
+	}
+	if err := cfg.profanity.Reload(context.Background()); err != nil {
+		t.Fatalf("unexpected error loading profanity list: %v", err)
+	}
+	return cfg.routes(newRateLimits(config.Default())), db
+}
+
+func doRequest(t *testing.T, api http.Handler, method, path, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			t.Fatalf("unexpected error encoding body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &reqBody)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeResponse[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.NewDecoder(rec.Body).Decode(&v); err != nil {
+		t.Fatalf("unexpected error decoding %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, expected int) {
+	t.Helper()
+	if rec.Code != expected {
+		t.Fatalf("expected status %d, but got %d: %s", expected, rec.Code, rec.Body.String())
+	}
+}
+
+// signUp creates a user and logs them in.
+func signUp(t *testing.T, api http.Handler, email string) TokenResponse {
+	t.Helper()
+	credentials := UserData{Email: email, Password: "hunter2"}
+	expectStatus(t, doRequest(t, api, "POST", "/api/users", "", credentials), 201)
+	rec := doRequest(t, api, "POST", "/api/login", "", credentials)
+	expectStatus(t, rec, 200)
+	return decodeResponse[TokenResponse](t, rec)
+}
+
+func TestChirpLifecycle(t *testing.T) {
+	api := newTestAPI(t)
+	walt := signUp(t, api, "walt@example.com")
+	jesse := signUp(t, api, "jesse@example.com")
+
+	rec := doRequest(t, api, "POST", "/api/chirps", walt.Token, receivedChirp{Body: "What a kerfuffle"})
+	expectStatus(t, rec, 201)
+	created := decodeResponse[chirp](t, rec)
+	if created.Body != "What a ****" || created.UserID != walt.ID {
+		t.Errorf("expected a masked chirp by walt, but got %+v", created)
+	}
+	chirpPath := "/api/chirps/" + created.ID.String()
+
+	expectStatus(t, doRequest(t, api, "POST", "/api/chirps", "", receivedChirp{Body: "anonymous"}), 401)
+	expectStatus(t, doRequest(t, api, "GET", chirpPath, "", nil), 200)
+	expectStatus(t, doRequest(t, api, "DELETE", chirpPath, jesse.Token, nil), 403)
+	expectStatus(t, doRequest(t, api, "DELETE", chirpPath, walt.Token, nil), 204)
+	expectStatus(t, doRequest(t, api, "GET", chirpPath, "", nil), 404)
+	expectStatus(t, doRequest(t, api, "POST", chirpPath+"/restore", jesse.Token, nil), 404)
+	expectStatus(t, doRequest(t, api, "POST", chirpPath+"/restore", walt.Token, nil), 200)
+	expectStatus(t, doRequest(t, api, "GET", chirpPath, "", nil), 200)
+}
+
+func TestBlockedAuthorsAreHidden(t *testing.T) {
+	api := newTestAPI(t)
+	walt := signUp(t, api, "walt@example.com")
+	jesse := signUp(t, api, "jesse@example.com")
+	expectStatus(t, doRequest(t, api, "POST", "/api/chirps", jesse.Token, receivedChirp{Body: "Yeah science!"}), 201)
+	expectStatus(t, doRequest(t, api, "POST", "/api/users/"+jesse.ID.String()+"/block", walt.Token, nil), 204)
+
+	testcases := []struct {
+		name          string
+		token         string
+		expectedcount int
+	}{
+		{name: "anonymous", expectedcount: 1},
+		{name: "blocker", token: walt.Token, expectedcount: 0},
+		{name: "author", token: jesse.Token, expectedcount: 1},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := doRequest(t, api, "GET", "/api/chirps", tc.token, nil)
+			expectStatus(t, rec, 200)
+			if chirps := decodeResponse[[]chirp](t, rec); len(chirps) != tc.expectedcount {
+				t.Errorf("expected %d chirps, but got %d", tc.expectedcount, len(chirps))
+			}
+		})
+	}
+}
+
+func TestReportingTwiceConflicts(t *testing.T) {
+	api := newTestAPI(t)
+	walt := signUp(t, api, "walt@example.com")
+	jesse := signUp(t, api, "jesse@example.com")
+	rec := doRequest(t, api, "POST", "/api/chirps", walt.Token, receivedChirp{Body: "Say my name"})
+	expectStatus(t, rec, 201)
+	reportPath := "/api/chirps/" + decodeResponse[chirp](t, rec).ID.String() + "/report"
+
+	expectStatus(t, doRequest(t, api, "POST", reportPath, jesse.Token, receivedReport{Reason: "harassment"}), 201)
+	expectStatus(t, doRequest(t, api, "POST", reportPath, jesse.Token, receivedReport{Reason: "harassment"}), 409)
+}
+
+func TestReportingHiddenChirpNotFound(t *testing.T) {
+	api, db := newTestAPIWithDB(t)
+	admin := signUp(t, api, "admin@example.com")
+	db.MakeAdmin(admin.ID)
+	walt := signUp(t, api, "walt@example.com")
+	jesse := signUp(t, api, "jesse@example.com")
+	rec := doRequest(t, api, "POST", "/api/chirps", walt.Token, receivedChirp{Body: "Say my name"})
+	expectStatus(t, rec, 201)
+	chirpPath := "/chirps/" + decodeResponse[chirp](t, rec).ID.String()
+
+	expectStatus(t, doRequest(t, api, "POST", "/admin"+chirpPath+"/hide", admin.Token, nil), 200)
+	expectStatus(t, doRequest(t, api, "POST", "/api"+chirpPath+"/report", jesse.Token, receivedReport{Reason: "harassment"}), 404)
+}
+
+func TestResolvingReportTwiceConflicts(t *testing.T) {
+	api, db := newTestAPIWithDB(t)
+	admin := signUp(t, api, "admin@example.com")
+	db.MakeAdmin(admin.ID)
+	walt := signUp(t, api, "walt@example.com")
+	rec := doRequest(t, api, "POST", "/api/chirps", walt.Token, receivedChirp{Body: "Say my name"})
+	expectStatus(t, rec, 201)
+	rec = doRequest(t, api, "POST", "/api/chirps/"+decodeResponse[chirp](t, rec).ID.String()+"/report", admin.Token, receivedReport{Reason: "spam"})
+	expectStatus(t, rec, 201)
+	reportPath := "/admin/reports/" + decodeResponse[report](t, rec).ID.String()
+
+	expectStatus(t, doRequest(t, api, "POST", reportPath+"/dismiss", admin.Token, nil), 200)
+	expectStatus(t, doRequest(t, api, "POST", reportPath+"/dismiss", admin.Token, nil), 409)
+	expectStatus(t, doRequest(t, api, "POST", reportPath+"/suspend-author", admin.Token, nil), 409)
+	expectStatus(t, doRequest(t, api, "POST", "/admin/reports/"+uuid.NewString()+"/dismiss", admin.Token, nil), 404)
+}
+
+func TestProblemResponses(t *testing.T) {
+	api := newTestAPI(t)
+	walt := signUp(t, api, "walt@example.com")
+	testcases := []struct {
+		name             string
+		method           string
+		path             string
+		token            string
+		body             string
+		expectedstatus   int
+		expectedtype     string
+		expectedfields   []string
+		expectedinstance string
+	}{
+		{name: "malformed json", method: "POST", path: "/api/chirps", token: walt.Token, body: `{"body":`, expectedstatus: 400, expectedtype: "/problems/malformed-request"},
+		{name: "empty body", method: "POST", path: "/api/login", expectedstatus: 400, expectedtype: "/problems/malformed-request"},
+		{name: "wrong field type", method: "POST", path: "/api/chirps", token: walt.Token, body: `{"body":42}`, expectedstatus: 400, expectedtype: "/problems/invalid-input", expectedfields: []string{"body"}},
+		{name: "chirp too long", method: "POST", path: "/api/chirps", token: walt.Token, body: `{"body":"` + strings.Repeat("a", 141) + `"}`, expectedstatus: 400, expectedtype: "/problems/invalid-input", expectedfields: []string{"body"}},
+		{name: "invalid chirp id", method: "GET", path: "/api/chirps/nope", expectedstatus: 400, expectedtype: "/problems/invalid-input", expectedfields: []string{"chirpID"}, expectedinstance: "/api/chirps/nope"},
+		{name: "missing chirp", method: "GET", path: "/api/chirps/" + uuid.NewString(), expectedstatus: 404, expectedtype: "/problems/not-found"},
+		{name: "missing token", method: "POST", path: "/api/chirps", body: `{"body":"hi"}`, expectedstatus: 401, expectedtype: "/problems/unauthorized"},
+		{name: "unknown field", method: "POST", path: "/api/chirps", token: walt.Token, body: `{"body":"hi","author":"walt"}`, expectedstatus: 400, expectedtype: "/problems/invalid-input", expectedfields: []string{"author"}},
+		{name: "trailing data", method: "POST", path: "/api/chirps", token: walt.Token, body: `{"body":"hi"} {}`, expectedstatus: 400, expectedtype: "/problems/malformed-request"},
+		{name: "body too large", method: "POST", path: "/api/chirps", token: walt.Token, body: `{"body":"` + strings.Repeat("a", maxBodyBytes) + `"}`, expectedstatus: 413, expectedtype: "/problems/content-too-large"},
+		{name: "empty chirp", method: "POST", path: "/api/chirps", token: walt.Token, body: `{"body":"  "}`, expectedstatus: 400, expectedtype: "/problems/invalid-input", expectedfields: []string{"body"}},
+		{name: "every invalid field", method: "POST", path: "/api/users", body: `{"email":"walt","password":""}`, expectedstatus: 400, expectedtype: "/problems/invalid-input", expectedfields: []string{"email", "password"}},
+		{name: "unknown report reason", method: "POST", path: "/api/chirps/" + uuid.NewString() + "/report", token: walt.Token, body: `{"reason":"boring"}`, expectedstatus: 400, expectedtype: "/problems/invalid-input", expectedfields: []string{"reason"}},
+		{name: "duplicate email", method: "POST", path: "/api/users", body: `{"email":"walt@example.com","password":"hunter2"}`, expectedstatus: 409, expectedtype: "/problems/conflict"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rec := httptest.NewRecorder()
+			api.ServeHTTP(rec, req)
+			expectStatus(t, rec, tc.expectedstatus)
+			if contentType := rec.Header().Get("Content-Type"); contentType != problem.ContentType {
+				t.Errorf("expected content type %q, but got %q", problem.ContentType, contentType)
+			}
+			details := decodeResponse[problem.Details](t, rec)
+			if details.Type != tc.expectedtype || details.Status != tc.expectedstatus || details.Title == "" {
+				t.Errorf("expected a %s problem with status %d, but got %+v", tc.expectedtype, tc.expectedstatus, details)
+			}
+			if tc.expectedinstance != "" && details.Instance != tc.expectedinstance {
+				t.Errorf("expected instance %q, but got %q", tc.expectedinstance, details.Instance)
+			}
+			var fields []string
+			for _, fieldErr := range details.Errors {
+				fields = append(fields, fieldErr.Field)
+			}
+			if !slices.Equal(fields, tc.expectedfields) {
+				t.Errorf("expected errors for fields %v, but got %+v", tc.expectedfields, details.Errors)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trustedProxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	testcases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		expected     string
+	}{
+		{name: "direct", remoteAddr: "192.0.2.1:1234", expected: "192.0.2.1"},
+		{name: "spoofed by a client", remoteAddr: "192.0.2.1:1234", forwardedFor: "198.51.100.7", expected: "192.0.2.1"},
+		{name: "through a trusted proxy", remoteAddr: "10.0.0.1:1234", forwardedFor: "198.51.100.7", expected: "198.51.100.7"},
+		{name: "spoofed through a trusted proxy", remoteAddr: "10.0.0.1:1234", forwardedFor: "203.0.113.9, 198.51.100.7, 10.0.0.2", expected: "198.51.100.7"},
+		{name: "garbage from a trusted proxy", remoteAddr: "10.0.0.1:1234", forwardedFor: "nonsense", expected: "10.0.0.1"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/chirps", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			}
+			if got := resolveClientIP(req, trustedProxies); got != tc.expected {
+				t.Errorf("expected %s, but got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	api := newTestAPI(t)
+	walt := signUp(t, api, "walt@example.com")
+	update := userUpdateData{NewEmail: "heisenberg@example.com", NewPassword: "blue-sky"}
+	expectStatus(t, doRequest(t, api, "PUT", "/api/users", walt.Token, update), 400)
+	update.CurrentPassword = "nope"
+	expectStatus(t, doRequest(t, api, "PUT", "/api/users", walt.Token, update), 403)
+	expectStatus(t, doRequest(t, api, "POST", "/api/login", "", UserData{Email: "heisenberg@example.com", Password: "blue-sky"}), 401)
+
+	update.CurrentPassword = "hunter2"
+	expectStatus(t, doRequest(t, api, "PUT", "/api/users", walt.Token, update), 200)
+	expectStatus(t, doRequest(t, api, "POST", "/api/login", "", UserData{Email: "heisenberg@example.com", Password: "blue-sky"}), 200)
+}
+
+func TestUpgradeWebhook(t *testing.T) {
+	api, db := newTestAPIWithDB(t)
+	walt := signUp(t, api, "walt@example.com")
+	// Polka may add fields at any time, so unknown ones are ignored.
+	body := `{"event": "user.upgraded", "data": {"user_id": "` + walt.ID.String() + `", "plan": "red"}, "sent_at": "2026-01-01T00:00:00Z"}`
+	for _, tc := range []struct {
+		key      string
+		expected int
+	}{
+		{key: "wrong-key", expected: 401},
+		{key: "test-polka-key", expected: 204},
+	} {
+		req := httptest.NewRequest("POST", "/api/polka/webhooks", strings.NewReader(body))
+		req.Header.Set("Authorization", "ApiKey "+tc.key)
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, req)
+		expectStatus(t, rec, tc.expected)
+	}
+	if user, err := db.GetUserByID(context.Background(), walt.ID); err != nil || !user.IsChirpyRed {
+		t.Errorf("expected walt to be upgraded, but got %+v, %v", user, err)
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	testcases := []struct {
+		name           string
+		contenttype    string
+		body           string
+		expectedstatus int
+		expectedemail  string
+		expectedlogin  string
+	}{
+		{name: "nothing", body: `{}`, expectedstatus: 200, expectedemail: "walt@example.com", expectedlogin: "hunter2"},
+		{name: "email only", body: `{"email":"heisenberg@example.com","current_password":"hunter2"}`, expectedstatus: 200, expectedemail: "heisenberg@example.com", expectedlogin: "hunter2"},
+		{name: "password only", body: `{"password":"blue-sky","current_password":"hunter2"}`, expectedstatus: 200, expectedemail: "walt@example.com", expectedlogin: "blue-sky"},
+		{name: "merge patch content type", contenttype: "application/merge-patch+json", body: `{"password":"blue-sky","current_password":"hunter2"}`, expectedstatus: 200, expectedemail: "walt@example.com", expectedlogin: "blue-sky"},
+		{name: "missing current password", body: `{"password":"blue-sky"}`, expectedstatus: 400, expectedemail: "walt@example.com", expectedlogin: "hunter2"},
+		{name: "wrong current password", body: `{"password":"blue-sky","current_password":"nope"}`, expectedstatus: 403, expectedemail: "walt@example.com", expectedlogin: "hunter2"},
+		{name: "removing the email", body: `{"email":null,"current_password":"hunter2"}`, expectedstatus: 400, expectedemail: "walt@example.com", expectedlogin: "hunter2"},
+		{name: "email in use", body: `{"email":"jesse@example.com","current_password":"hunter2"}`, expectedstatus: 409, expectedemail: "walt@example.com", expectedlogin: "hunter2"},
+		{name: "not json", contenttype: "text/plain", body: `{}`, expectedstatus: 415, expectedemail: "walt@example.com", expectedlogin: "hunter2"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			walt := signUp(t, api, "walt@example.com")
+			signUp(t, api, "jesse@example.com")
+			req := httptest.NewRequest("PATCH", "/api/users", strings.NewReader(tc.body))
+			req.Header.Set("Authorization", "Bearer "+walt.Token)
+			req.Header.Set("Content-Type", "application/json")
+			if tc.contenttype != "" {
+				req.Header.Set("Content-Type", tc.contenttype)
+			}
+			rec := httptest.NewRecorder()
+			api.ServeHTTP(rec, req)
+			expectStatus(t, rec, tc.expectedstatus)
+
+			rec = doRequest(t, api, "POST", "/api/login", "", UserData{Email: tc.expectedemail, Password: tc.expectedlogin})
+			expectStatus(t, rec, 200)
+			if user := decodeResponse[TokenResponse](t, rec).User; user.ID != walt.User.ID {
+				t.Errorf("expected to log in as walt, but got %+v", user)
+			}
+		})
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	api := newTestAPI(t)
+	walt := signUp(t, api, "walt@example.com")
+	jesse := signUp(t, api, "jesse@example.com")
+
+	patch := map[string]any{"handle": "Heisenberg", "display_name": "Walter White", "bio": "Say my name"}
+	rec := doRequest(t, api, "PATCH", "/api/users", walt.Token, patch)
+	expectStatus(t, rec, 200)
+	if user := decodeResponse[User](t, rec); user.Handle != "heisenberg" || user.DisplayName != "Walter White" {
+		t.Errorf("expected the handle in lower case and the display name, but got %+v", user)
+	}
+	expectStatus(t, doRequest(t, api, "PATCH", "/api/users", jesse.Token, map[string]any{"handle": "heisenberg"}), 409)
+	expectStatus(t, doRequest(t, api, "PATCH", "/api/users", jesse.Token, map[string]any{"handle": "no spaces"}), 400)
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	req := httptest.NewRequest("PUT", "/api/users/avatar", bytes.NewReader(png))
+	req.Header.Set("Authorization", "Bearer "+walt.Token)
+	rec = httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+	expectStatus(t, rec, 204)
+	avatarURL := rec.Header().Get("Location")
+
+	req = httptest.NewRequest("PUT", "/api/users/avatar", strings.NewReader("not an image"))
+	req.Header.Set("Authorization", "Bearer "+jesse.Token)
+	rec = httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+	expectStatus(t, rec, 415)
+
+	rec = doRequest(t, api, "GET", avatarURL, "", nil)
+	expectStatus(t, rec, 200)
+	if contentType := rec.Header().Get("Content-Type"); contentType != "image/png" || !bytes.Equal(rec.Body.Bytes(), png) {
+		t.Errorf("expected the uploaded PNG, but got %q (%s)", rec.Body.Bytes(), contentType)
+	}
+
+	expectStatus(t, doRequest(t, api, "POST", "/api/chirps", walt.Token, receivedChirp{Body: "I am the one who knocks"}), 201)
+	rec = doRequest(t, api, "GET", "/api/users/HEISENBERG", "", nil)
+	expectStatus(t, rec, 200)
+	if profile := decodeResponse[profile](t, rec); profile.ID != walt.User.ID || profile.ChirpCount != 1 || profile.Bio != "Say my name" || profile.AvatarURL != avatarURL {
+		t.Errorf("expected walt's profile with 1 chirp and an avatar, but got %+v", profile)
+	}
+	expectStatus(t, doRequest(t, api, "GET", "/api/users/pinkman", "", nil), 404)
+
+	rec = doRequest(t, api, "GET", "/api/chirps", "", nil)
+	expectStatus(t, rec, 200)
+	chirps := decodeResponse[[]chirp](t, rec)
+	if len(chirps) != 1 || chirps[0].Author == nil {
+		t.Fatalf("expected 1 chirp with its author, but got %+v", chirps)
+	}
+	expected := author{ID: walt.User.ID, Handle: "heisenberg", DisplayName: "Walter White", AvatarURL: avatarURL}
+	if *chirps[0].Author != expected {
+		t.Errorf("expected author %+v, but got %+v", expected, *chirps[0].Author)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	api := newTestAPI(t)
+	walt := signUp(t, api, "walt@example.com")
+	rec := doRequest(t, api, "POST", "/api/chirps", walt.Token, receivedChirp{Body: "I am the one who knocks"})
+	expectStatus(t, rec, 201)
+	chirpPath := "/api/chirps/" + decodeResponse[chirp](t, rec).ID.String()
+
+	expectStatus(t, doRequest(t, api, "DELETE", "/api/users", walt.Token, map[string]any{}), 400)
+	expectStatus(t, doRequest(t, api, "DELETE", "/api/users", walt.Token, userDeleteData{Password: "nope"}), 403)
+	rec = doRequest(t, api, "DELETE", "/api/users", walt.Token, userDeleteData{Password: "hunter2"})
+	expectStatus(t, rec, 202)
+	if pending := decodeResponse[pendingDeletion](t, rec); pending.PurgeAfter.Sub(pending.DeletedAt) != time.Hour {
+		t.Errorf("expected the purge to be one grace period after the deletion, but got %+v", pending)
+	}
+	expectStatus(t, doRequest(t, api, "POST", "/api/refresh", walt.RefreshToken, nil), 401)
+	expectStatus(t, doRequest(t, api, "POST", "/api/chirps", walt.Token, receivedChirp{Body: "Say my name"}), 401)
+	expectStatus(t, doRequest(t, api, "GET", chirpPath, "", nil), 404)
+	rec = doRequest(t, api, "GET", "/api/chirps", "", nil)
+	expectStatus(t, rec, 200)
+	if chirps := decodeResponse[[]chirp](t, rec); len(chirps) != 0 {
+		t.Errorf("expected chirps by a deleted account to be left out, but got %+v", chirps)
+	}
+
+	rec = doRequest(t, api, "POST", "/api/login", "", UserData{Email: "walt@example.com", Password: "hunter2"})
+	expectStatus(t, rec, 200)
+	walt = decodeResponse[TokenResponse](t, rec)
+	if exported := exportUser(t, api, walt.Token); strings.Contains(string(exported["user.json"]), `"deleted_at"`) {
+		t.Errorf("expected logging in to cancel the deletion, but got %s", exported["user.json"])
+	}
+	expectStatus(t, doRequest(t, api, "GET", chirpPath, "", nil), 200)
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	api, db := newTestAPIWithDB(t)
+	walt := signUp(t, api, "walt@example.com")
+	patch := map[string]any{"email": "heisenberg@example.com", "current_password": "hunter2"}
+	expectStatus(t, doRequest(t, api, "PATCH", "/api/users", walt.Token, patch), 200)
+	failed := UserData{Email: "heisenberg@example.com", Password: "nope"}
+	expectStatus(t, doRequest(t, api, "POST", "/api/login", "", failed), 401)
+	_, err := db.SetUserDeleted(context.Background(), database.SetUserDeletedParams{
+		DeletedAt: sql.NullTime{Time: time.Now().UTC().Add(-2 * time.Hour), Valid: true},
+		UpdatedAt: time.Now().UTC(),
+		ID:        walt.ID,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := &apiConfig{dbQueries: db, tx: db, accountDeletionGracePeriod: time.Hour}
+	cfg.purgeDeletedUsers(context.Background())
+	if _, err := db.GetUserByID(context.Background(), walt.ID); err != sql.ErrNoRows {
+		t.Fatalf("expected walt to be purged, but got %v", err)
+	}
+	events, err := db.GetSecurityLog(context.Background(), database.GetSecurityLogParams{
+		UserID: uuid.NullUUID{UUID: walt.ID, Valid: true},
+		Limit:  10,
+	})
+	if err != nil || len(events) != 3 {
+		t.Fatalf("expected walt's login, email change and failed login to outlive him, but got %+v, %v", events, err)
+	}
+	for _, event := range events {
+		if event.Ip != "" || event.UserAgent != "" || bytes.Contains(event.Metadata, []byte("@example.com")) {
+			t.Errorf("expected the event to be pseudonymized, but got %+v with %s", event, event.Metadata)
+		}
+	}
+	var metadata map[string]string
+	if err := json.Unmarshal(events[1].Metadata, &metadata); err != nil || len(metadata["email_sha256"]) != 64 || len(metadata["previous_email_sha256"]) != 64 {
+		t.Errorf("expected hashes of both emails, but got %s (%v)", events[1].Metadata, err)
+	}
+}
+
+func TestExportUser(t *testing.T) {
+	api := newTestAPI(t)
+	walt := signUp(t, api, "walt@example.com")
+	jesse := signUp(t, api, "jesse@example.com")
+	expectStatus(t, doRequest(t, api, "POST", "/api/chirps", walt.Token, receivedChirp{Body: "I am the one who knocks"}), 201)
+	expectStatus(t, doRequest(t, api, "POST", "/api/chirps", jesse.Token, receivedChirp{Body: "Yeah science!"}), 201)
+	expectStatus(t, doRequest(t, api, "POST", "/api/users/"+jesse.ID.String()+"/block", walt.Token, nil), 204)
+
+	expectStatus(t, doRequest(t, api, "POST", "/api/users/export", "", nil), 401)
+	exported := exportUser(t, api, walt.Token)
+	for _, name := range []string{"user.json", "chirps.json", "refresh_tokens.json", "reports.json", "blocks.json", "mutes.json", "security_log.json"} {
+		if _, ok := exported[name]; !ok {
+			t.Errorf("expected %s in the export, but got only %v", name, slices.Collect(maps.Keys(exported)))
+		}
+	}
+	var chirps []exportedChirp
+	if err := json.Unmarshal(exported["chirps.json"], &chirps); err != nil || len(chirps) != 1 || chirps[0].UserID != walt.ID {
+		t.Errorf("expected walt's only chirp, but got %s (%v)", exported["chirps.json"], err)
+	}
+	var blocks []exportedRelation
+	if err := json.Unmarshal(exported["blocks.json"], &blocks); err != nil || len(blocks) != 1 || blocks[0].UserID != jesse.ID {
+		t.Errorf("expected walt to block jesse, but got %s (%v)", exported["blocks.json"], err)
+	}
+	var securityLog []auditEvent
+	if err := json.Unmarshal(exported["security_log.json"], &securityLog); err != nil || len(securityLog) != 1 || securityLog[0].Action != auditLogin {
+		t.Errorf("expected walt's login, but got %s (%v)", exported["security_log.json"], err)
+	}
+	for name, content := range exported {
+		if bytes.Contains(content, []byte(walt.RefreshToken)) {
+			t.Errorf("expected %s not to contain the refresh token", name)
+		}
+	}
+}
+
+// exportUser downloads the user's export and returns its files by name.
+func exportUser(t *testing.T, api http.Handler, token string) map[string][]byte {
+	t.Helper()
+	rec := doRequest(t, api, "POST", "/api/users/export", token, nil)
+	expectStatus(t, rec, 200)
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("unexpected error reading export: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("unexpected error opening %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("unexpected error reading %s: %v", f.Name, err)
+		}
+		files[f.Name] = content
+	}
+	return files
+}
+
+func TestAdminUsers(t *testing.T) {
+	api, db := newTestAPIWithDB(t)
+	admin := signUp(t, api, "admin@example.com")
+	db.MakeAdmin(admin.ID)
+	walt := signUp(t, api, "walt@example.com")
+	signUp(t, api, "jesse_pinkman@example.com")
+	userPath := "/admin/users/" + walt.ID.String()
+
+	expectStatus(t, doRequest(t, api, "GET", "/admin/users", walt.Token, nil), 403)
+	expectStatus(t, doRequest(t, api, "GET", "/admin/users?limit=0", admin.Token, nil), 400)
+	rec := doRequest(t, api, "GET", "/admin/users?limit=2", admin.Token, nil)
+	expectStatus(t, rec, 200)
+	if page := decodeResponse[userPage](t, rec); len(page.Users) != 2 || page.NextOffset == nil || *page.NextOffset != 2 {
+		t.Errorf("expected a full first page with more to come, but got %+v", page)
+	}
+	rec = doRequest(t, api, "GET", "/admin/users?email=_", admin.Token, nil)
+	expectStatus(t, rec, 200)
+	if page := decodeResponse[userPage](t, rec); len(page.Users) != 1 || page.NextOffset != nil {
+		t.Errorf("expected only the email with an underscore, but got %+v", page)
+	}
+
+	rec = doRequest(t, api, "POST", userPath+"/grant-chirpy-red", admin.Token, nil)
+	expectStatus(t, rec, 200)
+	if user := decodeResponse[userDetails](t, rec); !user.IsChirpyRed {
+		t.Errorf("expected walt to have Chirpy Red, but got %+v", user)
+	}
+	expectStatus(t, doRequest(t, api, "POST", userPath+"/suspend", admin.Token, nil), 200)
+	expectStatus(t, doRequest(t, api, "POST", "/api/refresh", walt.RefreshToken, nil), 401)
+	expectStatus(t, doRequest(t, api, "POST", "/api/chirps", walt.Token, receivedChirp{Body: "Say my name"}), 403)
+	expectStatus(t, doRequest(t, api, "POST", "/api/login", "", UserData{Email: "walt@example.com", Password: "hunter2"}), 403)
+	expectStatus(t, doRequest(t, api, "POST", userPath+"/unsuspend", admin.Token, nil), 200)
+	expectStatus(t, doRequest(t, api, "POST", "/api/login", "", UserData{Email: "walt@example.com", Password: "hunter2"}), 200)
+	expectStatus(t, doRequest(t, api, "POST", "/admin/users/"+uuid.NewString()+"/suspend", admin.Token, nil), 404)
+
+	rec = doRequest(t, api, "GET", userPath, admin.Token, nil)
+	expectStatus(t, rec, 200)
+	details := decodeResponse[userWithAudit](t, rec)
+	var actions []string
+	for _, event := range details.AuditEvents {
+		actions = append(actions, event.Action)
+		if strings.HasPrefix(event.Action, "admin.") && (event.ActorID == nil || *event.ActorID != admin.ID) {
+			t.Errorf("expected admin events to be by the admin, but got %+v", event)
+		}
+	}
+	expected := []string{auditLogin, auditUnsuspendUser, auditLoginFailed, auditSuspendUser, auditGrantChirpyRed, auditLogin}
+	if !slices.Equal(actions, expected) {
+		t.Errorf("expected audit events %v, but got %v", expected, actions)
+	}
+}
+
+func TestRequirePasswordReset(t *testing.T) {
+	// Logins are limited to 5 a minute, which is just enough for the happy path.
+	api, db := newTestAPIWithDB(t)
+	admin := signUp(t, api, "admin@example.com")
+	db.MakeAdmin(admin.ID)
+	walt := signUp(t, api, "walt@example.com")
+
+	expectStatus(t, doRequest(t, api, "POST", "/admin/users/"+walt.ID.String()+"/require-password-reset", admin.Token, nil), 200)
+	expectStatus(t, doRequest(t, api, "POST", "/api/refresh", walt.RefreshToken, nil), 401)
+	expectStatus(t, doRequest(t, api, "POST", "/api/login", "", UserData{Email: "walt@example.com", Password: "hunter2"}), 403)
+	expectStatus(t, doRequest(t, api, "POST", "/api/login", "", loginData{UserData: UserData{Email: "walt@example.com", Password: "hunter2"}, NewPassword: "blue-sky"}), 200)
+	expectStatus(t, doRequest(t, api, "POST", "/api/login", "", UserData{Email: "walt@example.com", Password: "blue-sky"}), 200)
+}
+
+func TestAuditLog(t *testing.T) {
+	api, db := newTestAPIWithDB(t)
+	admin := signUp(t, api, "admin@example.com")
+	db.MakeAdmin(admin.ID)
+	walt := signUp(t, api, "walt@example.com")
+	waltEvents := "/admin/audit?target_id=" + walt.ID.String()
+
+	expectStatus(t, doRequest(t, api, "POST", "/api/login", "", UserData{Email: "walt@example.com", Password: "nope"}), 401)
+	expectStatus(t, doRequest(t, api, "PUT", "/api/users", walt.Token, userUpdateData{NewEmail: "heisenberg@example.com", NewPassword: "blue-sky", CurrentPassword: "hunter2"}), 200)
+	expectStatus(t, doRequest(t, api, "POST", "/api/refresh", walt.RefreshToken, nil), 200)
+	expectStatus(t, doRequest(t, api, "POST", "/api/revoke", walt.RefreshToken, nil), 204)
+	expectStatus(t, doRequest(t, api, "POST", "/admin/users/"+walt.ID.String()+"/grant-chirpy-red", admin.Token, nil), 200)
+
+	rec := doRequest(t, api, "GET", waltEvents, admin.Token, nil)
+	expectStatus(t, rec, 200)
+	var actions []string
+	for _, event := range decodeResponse[auditPage](t, rec).Events {
+		actions = append(actions, event.Action)
+	}
+	expected := []string{auditGrantChirpyRed, auditTokenRevoke, auditTokenRefresh, auditPasswordChange, auditEmailChange, auditLoginFailed, auditLogin}
+	if !slices.Equal(actions, expected) {
+		t.Errorf("expected audit events %v, but got %v", expected, actions)
+	}
+
+	rec = doRequest(t, api, "GET", "/admin/audit?action=user.email_change", admin.Token, nil)
+	expectStatus(t, rec, 200)
+	page := decodeResponse[auditPage](t, rec)
+	if len(page.Events) != 1 || !strings.Contains(string(page.Events[0].Metadata), `"previous_email":"walt@example.com"`) {
+		t.Errorf("expected the email change with the previous email, but got %+v", page.Events)
+	}
+	rec = doRequest(t, api, "GET", waltEvents+"&limit=2", admin.Token, nil)
+	expectStatus(t, rec, 200)
+	if page := decodeResponse[auditPage](t, rec); len(page.Events) != 2 || page.NextOffset == nil || *page.NextOffset != 2 {
+		t.Errorf("expected a first page of 2 events, but got %+v", page)
+	}
+	expectStatus(t, doRequest(t, api, "GET", "/admin/audit?since=yesterday&actor_id=walt", admin.Token, nil), 400)
+	expectStatus(t, doRequest(t, api, "GET", "/admin/audit", walt.Token, nil), 403)
+
+	rec = doRequest(t, api, "GET", "/api/users/me/security-log", walt.Token, nil)
+	expectStatus(t, rec, 200)
+	events := decodeResponse[auditPage](t, rec).Events
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events in the security log, but got %+v", len(expected), events)
+	}
+	if grant := events[0]; grant.ActorID != nil || grant.IP != "" {
+		t.Errorf("expected the admin to be left out of the security log, but got %+v", grant)
+	}
+	if revoke := events[1]; revoke.ActorID == nil || *revoke.ActorID != walt.ID || revoke.IP == "" {
+		t.Errorf("expected walt's own events to be shown in full, but got %+v", revoke)
+	}
+}
+
+// browser keeps the cookies the dashboard sets, like a browser would.
+type browser struct {
+	t       *testing.T
+	api     http.Handler
+	cookies map[string]*http.Cookie
+}
+
+var csrfTokenPattern = regexp.MustCompile(`name="csrf_token" value="([0-9a-f]+)"`)
+
+func (b *browser) do(method, path string, form url.Values) *httptest.ResponseRecorder {
+	b.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for _, cookie := range b.cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	b.api.ServeHTTP(rec, req)
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(b.cookies, cookie.Name)
+		} else {
+			b.cookies[cookie.Name] = cookie
+		}
+	}
+	return rec
+}
+
+// csrfToken loads a page and takes the token from its forms.
+func (b *browser) csrfToken(path string) string {
+	b.t.Helper()
+	rec := b.do("GET", path, nil)
+	expectStatus(b.t, rec, 200)
+	match := csrfTokenPattern.FindStringSubmatch(rec.Body.String())
+	if match == nil {
+		b.t.Fatalf("expected a CSRF token in %s", rec.Body.String())
+	}
+	return match[1]
+}
+
+func expectRedirect(t *testing.T, rec *httptest.ResponseRecorder, location string) {
+	t.Helper()
+	expectStatus(t, rec, http.StatusSeeOther)
+	if got := rec.Header().Get("Location"); got != location {
+		t.Fatalf("expected a redirect to %s, but got %s", location, got)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	api, db := newTestAPIWithDB(t)
+	admin := signUp(t, api, "admin@example.com")
+	db.MakeAdmin(admin.ID)
+	walt := signUp(t, api, "walt@example.com")
+	rec := doRequest(t, api, "POST", "/api/chirps", walt.Token, receivedChirp{Body: "Say my name"})
+	expectStatus(t, rec, 201)
+	chirpID := decodeResponse[chirp](t, rec).ID
+	expectStatus(t, doRequest(t, api, "POST", "/api/chirps/"+chirpID.String()+"/report", admin.Token, receivedReport{Reason: "spam"}), 201)
+
+	b := &browser{t: t, api: api, cookies: map[string]*http.Cookie{}}
+	expectRedirect(t, b.do("GET", "/admin/", nil), "/admin/login")
+	token := b.csrfToken("/admin/login")
+	expectStatus(t, b.do("POST", "/admin/login", url.Values{"email": {"admin@example.com"}, "password": {"hunter2"}}), 403)
+	expectStatus(t, b.do("POST", "/admin/login", url.Values{"csrf_token": {token}, "email": {"walt@example.com"}, "password": {"hunter2"}}), 403)
+	expectRedirect(t, b.do("POST", "/admin/login", url.Values{"csrf_token": {token}, "email": {"admin@example.com"}, "password": {"hunter2"}}), "/admin/")
+
+	rec = b.do("GET", "/admin/", nil)
+	expectStatus(t, rec, 200)
+	for _, expected := range []string{"Say my name", "walt@example.com", "admin@example.com"} {
+		if !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("expected the dashboard to show %q", expected)
+		}
+	}
+	hidePath := "/admin/dashboard/chirps/" + chirpID.String() + "/hide"
+	expectStatus(t, b.do("POST", hidePath, url.Values{"csrf_token": {"forged"}}), 403)
+	expectRedirect(t, b.do("POST", hidePath, url.Values{"csrf_token": {token}}), "/admin/")
+	expectStatus(t, doRequest(t, api, "GET", "/api/chirps/"+chirpID.String(), "", nil), 404)
+	if rec := b.do("GET", "/admin/", nil); !strings.Contains(rec.Body.String(), "No open reports") {
+		t.Errorf("expected hiding the chirp to resolve its report, but got %s", rec.Body.String())
+	}
+
+	expectRedirect(t, b.do("POST", "/admin/logout", url.Values{"csrf_token": {token}}), "/admin/login")
+	expectRedirect(t, b.do("GET", "/admin/", nil), "/admin/login")
+}
+
+func TestDashboardRejectsDeletedAdmin(t *testing.T) {
+	api, db := newTestAPIWithDB(t)
+	admin := signUp(t, api, "admin@example.com")
+	db.MakeAdmin(admin.ID)
+	b := &browser{t: t, api: api, cookies: map[string]*http.Cookie{}}
+	token := b.csrfToken("/admin/login")
+	login := url.Values{"csrf_token": {token}, "email": {"admin@example.com"}, "password": {"hunter2"}}
+	expectRedirect(t, b.do("POST", "/admin/login", login), "/admin/")
+
+	_, err := db.SetUserDeleted(context.Background(), database.SetUserDeletedParams{
+		DeletedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		UpdatedAt: time.Now().UTC(),
+		ID:        admin.ID,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectRedirect(t, b.do("GET", "/admin/", nil), "/admin/login")
+	expectStatus(t, b.do("POST", "/admin/login", login), 401)
+}
+
+func TestFixtures(t *testing.T) {
+	api := newTestAPI(t)
+	signUp(t, api, "walt@example.com")
+	skyler := UserData{Email: "skyler@example.com", Password: "hunter2"}
+
+	rec := doRequest(t, api, "GET", "/admin/fixtures", "", nil)
+	expectStatus(t, rec, 200)
+	if list := decodeResponse[fixtureList](t, rec); !slices.Equal(list.Fixtures, []string{"cast"}) {
+		t.Errorf("expected the cast fixture, but got %+v", list)
+	}
+	expectStatus(t, doRequest(t, api, "POST", "/admin/reset?fixture=missing", "", nil), 404)
+	expectStatus(t, doRequest(t, api, "POST", "/admin/reset?fixture=../cast", "", nil), 400)
+	expectStatus(t, doRequest(t, api, "POST", "/admin/reset?fixture=cast", "", nil), 200)
+	expectStatus(t, doRequest(t, api, "POST", "/api/login", "", UserData{Email: "walt@example.com", Password: "hunter2"}), 401)
+	expectStatus(t, doRequest(t, api, "POST", "/admin/fixtures/cast", "", nil), 409)
+
+	expectStatus(t, doRequest(t, api, "POST", "/admin/snapshots/before", "", nil), 201)
+	rec = doRequest(t, api, "POST", "/api/login", "", skyler)
+	expectStatus(t, rec, 200)
+	token := decodeResponse[TokenResponse](t, rec).Token
+	expectStatus(t, doRequest(t, api, "POST", "/api/chirps", token, receivedChirp{Body: "Tread lightly"}), 201)
+	expectStatus(t, doRequest(t, api, "POST", "/admin/snapshots/before/restore", "", nil), 204)
+	rec = doRequest(t, api, "GET", "/api/chirps", "", nil)
+	expectStatus(t, rec, 200)
+	if chirps := decodeResponse[[]chirp](t, rec); len(chirps) != 1 {
+		t.Errorf("expected only the chirp from the fixture, but got %+v", chirps)
+	}
+	rec = doRequest(t, api, "GET", "/admin/snapshots/before", "", nil)
+	expectStatus(t, rec, 200)
+	if set := decodeResponse[fixtures.Set](t, rec); len(set.Users) != 1 || set.Users[0].Email != "skyler@example.com" || set.Users[0].HashedPassword == "" {
+		t.Errorf("expected a snapshot with skyler, but got %+v", set)
+	}
+	expectStatus(t, doRequest(t, api, "GET", "/admin/snapshots/after", "", nil), 404)
+	expectStatus(t, doRequest(t, api, "POST", "/admin/snapshots/after/restore", "", nil), 404)
+
+	expectStatus(t, doRequest(t, api, "POST", "/admin/reset", "", nil), 200)
+	expectStatus(t, doRequest(t, api, "POST", "/api/login", "", skyler), 401)
+
+	for _, platform := range []string{"staging", "production"} {
+		cfg := &apiConfig{platform: platform}
+		rec := httptest.NewRecorder()
+		cfg.reset(rec, httptest.NewRequest("POST", "/admin/reset", nil))
+		expectStatus(t, rec, 403)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/validate"
+)
+
+// Actions recorded in the audit log, prefixed with who can take them: users
+// acting on their own account, admins, or the payment webhook.
+const (
+	auditLogin            = "user.login"
+	auditLoginFailed      = "user.login_failed"
+	auditEmailChange      = "user.email_change"
+	auditPasswordChange   = "user.password_change"
+	auditTokenRefresh     = "user.token_refresh"
+	auditTokenRevoke      = "user.token_revoke"
+	auditDeletionRequest  = "user.deletion_request"
+	auditDeletionCancel   = "user.deletion_cancel"
+	auditDataExport       = "user.data_export"
+	auditChirpDelete      = "user.chirp_delete"
+	auditChirpyRedUpgrade = "webhook.chirpy_red_upgrade"
+
+	auditSuspendUser          = "admin.suspend_user"
+	auditUnsuspendUser        = "admin.unsuspend_user"
+	auditRequirePasswordReset = "admin.require_password_reset"
+	auditGrantChirpyRed       = "admin.grant_chirpy_red"
+	auditRevokeChirpyRed      = "admin.revoke_chirpy_red"
+	auditHideChirp            = "admin.hide_chirp"
+	auditRestoreChirp         = "admin.restore_chirp"
+	auditDismissReport        = "admin.dismiss_report"
+)
+
+type auditEvent struct {
+	ID        uuid.UUID       `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Action    string          `json:"action"`
+	ActorID   *uuid.UUID      `json:"actor_id,omitempty"`
+	TargetID  *uuid.UUID      `json:"target_id,omitempty"`
+	IP        string          `json:"ip"`
+	UserAgent string          `json:"user_agent"`
+	Metadata  json.RawMessage `json:"metadata"`
+}
+
+func auditEventFromDB(dbEvent database.AuditEvent) auditEvent {
+	event := auditEvent{
+		ID:        dbEvent.ID,
+		CreatedAt: dbEvent.CreatedAt,
+		Action:    dbEvent.Action,
+		IP:        dbEvent.Ip,
+		UserAgent: dbEvent.UserAgent,
+		Metadata:  dbEvent.Metadata,
+	}
+	if dbEvent.ActorID.Valid {
+		event.ActorID = &dbEvent.ActorID.UUID
+	}
+	if dbEvent.TargetID.Valid {
+		event.TargetID = &dbEvent.TargetID.UUID
+	}
+	return event
+}
+
+// audit records that actor did action to target. Pass the transaction making
+// the change, so there's never a change without its record or the other way
+// round. Either user may be uuid.Nil when there isn't one.
+func audit(ctx context.Context, q database.Querier, r *http.Request, action string, actor, target uuid.UUID, metadata map[string]any) error {
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return q.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		Action:    action,
+		ActorID:   uuid.NullUUID{UUID: actor, Valid: actor != uuid.Nil},
+		TargetID:  uuid.NullUUID{UUID: target, Valid: target != uuid.Nil},
+		Ip:        clientIP(r),
+		UserAgent: r.UserAgent(),
+		Metadata:  encoded,
+	})
+}
+
+// auditFailedLogin records a login that was turned away. The login's
+// transaction has already rolled back, so it's written on its own. target is
+// uuid.Nil when nobody has the email. Errors other than wrong credentials or
+// a locked account aren't the user's doing and aren't recorded.
+func (cfg *apiConfig) auditFailedLogin(r *http.Request, email string, target uuid.UUID, loginErr error) {
+	var reason string
+	switch {
+	case errors.Is(loginErr, errIncorrectLogin):
+		reason = "incorrect_login"
+	case errors.Is(loginErr, errAccountSuspended):
+		reason = "suspended"
+	case errors.Is(loginErr, errPasswordResetRequired):
+		reason = "password_reset_required"
+	case errors.Is(loginErr, errAccountDeleted):
+		reason = "deletion_pending"
+	default:
+		return
+	}
+	err := audit(r.Context(), cfg.dbQueries, r, auditLoginFailed, uuid.Nil, target, map[string]any{"email": email, "reason": reason})
+	if err != nil {
+		loggerFrom(r.Context()).Error("Failed to record failed login", "error", err)
+	}
+}
+
+type auditPage struct {
+	Events     []auditEvent `json:"events"`
+	NextOffset *int         `json:"next_offset,omitempty"`
+}
+
+// newAuditPage is given one row more than the limit when there's another
+// page.
+func newAuditPage(dbEvents []database.AuditEvent, limit, offset int) auditPage {
+	page := auditPage{Events: []auditEvent{}}
+	if len(dbEvents) > limit {
+		dbEvents = dbEvents[:limit]
+		next := offset + limit
+		page.NextOffset = &next
+	}
+	for _, dbEvent := range dbEvents {
+		page.Events = append(page.Events, auditEventFromDB(dbEvent))
+	}
+	return page
+}
+
+// listAuditEvents searches the whole audit log. Every filter is optional;
+// since and until are RFC 3339 times and the range includes since but not
+// until.
+func (cfg *apiConfig) listAuditEvents(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.authenticateAdmin(w, r); !ok {
+		return
+	}
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	params, err := parseAuditFilters(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	params.Limit = int32(limit + 1)
+	params.Offset = int32(offset)
+	dbEvents, err := cfg.dbQueries.ListAuditEvents(r.Context(), params)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, newAuditPage(dbEvents, limit, offset))
+}
+
+func parseAuditFilters(r *http.Request) (database.ListAuditEventsParams, error) {
+	v := validate.Validator{}
+	query := r.URL.Query()
+	params := database.ListAuditEventsParams{}
+	if s := query.Get("action"); s != "" {
+		params.Action = sql.NullString{String: s, Valid: true}
+	}
+	for _, filter := range []struct {
+		name  string
+		value *uuid.NullUUID
+	}{
+		{"actor_id", &params.ActorID},
+		{"target_id", &params.TargetID},
+	} {
+		if s := query.Get(filter.name); s != "" {
+			id, err := uuid.Parse(s)
+			v.Check(err == nil, filter.name, "must be a UUID")
+			*filter.value = uuid.NullUUID{UUID: id, Valid: err == nil}
+		}
+	}
+	for _, filter := range []struct {
+		name  string
+		value *sql.NullTime
+	}{
+		{"since", &params.Since},
+		{"until", &params.Until},
+	} {
+		if s := query.Get(filter.name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			v.Check(err == nil, filter.name, "must be an RFC 3339 time")
+			*filter.value = sql.NullTime{Time: t.UTC(), Valid: err == nil}
+		}
+	}
+	return params, v.Err()
+}
+
+// getSecurityLog shows users what happened to their account, whoever did it.
+// Who an admin was and where they connected from is kept to the admins.
+func (cfg *apiConfig) getSecurityLog(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	dbEvents, err := cfg.dbQueries.GetSecurityLog(r.Context(), database.GetSecurityLogParams{
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Limit:  int32(limit + 1),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	page := newAuditPage(dbEvents, limit, offset)
+	for i, event := range page.Events {
+		page.Events[i] = event.forUser(userID)
+	}
+	respondWithJSON(w, 200, page)
+}
+
+// forUser hides who the actor was and where they connected from unless it's
+// the user the event is shown to.
+func (e auditEvent) forUser(userID uuid.UUID) auditEvent {
+	if e.ActorID != nil && *e.ActorID != userID {
+		e.ActorID = nil
+		e.IP = ""
+		e.UserAgent = ""
+	}
+	return e
+}
+
+// pseudonymizedFields are the metadata keys holding emails. A purged user's
+// events keep a hash of each, so the same address can still be matched up
+// across events without being readable.
+var pseudonymizedFields = []string{"email", "previous_email"}
+
+// pseudonymizeAuditEvents clears the IP address and user agent of every event
+// the user was involved in and replaces emails in the metadata with their
+// SHA-256. It's the one change the database lets through on audit events.
+func pseudonymizeAuditEvents(ctx context.Context, q database.Querier, userID uuid.UUID) error {
+	const pageSize = 100
+	for offset := 0; ; offset += pageSize {
+		dbEvents, err := q.GetSecurityLog(ctx, database.GetSecurityLogParams{
+			UserID: uuid.NullUUID{UUID: userID, Valid: true},
+			Limit:  pageSize,
+			Offset: int32(offset),
+		})
+		if err != nil {
+			return err
+		}
+		for _, dbEvent := range dbEvents {
+			metadata, changed, err := pseudonymizeMetadata(dbEvent.Metadata)
+			if err != nil {
+				return err
+			}
+			if !changed && dbEvent.Ip == "" && dbEvent.UserAgent == "" {
+				continue
+			}
+			err = q.PseudonymizeAuditEvent(ctx, database.PseudonymizeAuditEventParams{
+				ID:       dbEvent.ID,
+				Metadata: metadata,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if len(dbEvents) < pageSize {
+			return nil
+		}
+	}
+}
+
+func pseudonymizeMetadata(encoded json.RawMessage) (json.RawMessage, bool, error) {
+	var metadata map[string]any
+	if err := json.Unmarshal(encoded, &metadata); err != nil {
+		return nil, false, err
+	}
+	changed := false
+	for _, field := range pseudonymizedFields {
+		email, ok := metadata[field].(string)
+		if !ok {
+			continue
+		}
+		delete(metadata, field)
+		sum := sha256.Sum256([]byte(strings.ToLower(email)))
+		metadata[field+"_sha256"] = hex.EncodeToString(sum[:])
+		changed = true
+	}
+	if !changed {
+		return encoded, false, nil
+	}
+	encoded, err := json.Marshal(metadata)
+	return encoded, true, err
+}
@@ -1,46 +1,102 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"log/slog"
 	"net/http"
+	"time"
 
-	"github.com/google/uuid"
-	"github.com/marekbrze/chirpy/internal/auth"
+	"github.com/marekbrze/chirpy/internal/database"
 )
 
 func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
-	headerToken, err := auth.GetBearerToken(r.Header)
+	userID, err := cfg.authenticate(r)
 	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+		respondWithError(w, r, err)
 		return
 	}
-	userID, err := auth.ValidateJWT(headerToken, cfg.jwtSecret)
+	chirpID, err := parseUUIDPathValue(r, "chirpID")
 	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+		respondWithError(w, r, err)
 		return
 	}
-	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	err = cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		dbChirp, err := q.GetChirp(r.Context(), chirpID)
+		if err != nil {
+			return err
+		}
+		if userID != dbChirp.UserID {
+			return errNotChirpAuthor
+		}
+		err = q.DeleteChirp(r.Context(), database.DeleteChirpParams{
+			DeletedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			UpdatedAt: time.Now().UTC(),
+			ID:        dbChirp.ID,
+		})
+		if err != nil {
+			return err
+		}
+		return audit(r.Context(), q, r, auditChirpDelete, userID, userID, map[string]any{"chirp_id": dbChirp.ID})
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, r, errChirpNotFound)
+			return
+		}
+		respondWithError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) restoreChirp(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	chirpID, err := parseUUIDPathValue(r, "chirpID")
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, r, err)
 		return
 	}
-	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	dbChirp, err := cfg.dbQueries.RestoreChirp(r.Context(), database.RestoreChirpParams{
+		UpdatedAt: time.Now().UTC(),
+		ID:        chirpID,
+		UserID:    userID,
+		DeletedAt: sql.NullTime{Time: time.Now().UTC().Add(-cfg.chirpRestoreGracePeriod), Valid: true},
+	})
 	if err != nil {
-		if err == sql.ErrNoRows && userID == dbChirp.UserID {
-			respondWithError(w, 404, "Chirp doesn't exist")
+		if err == sql.ErrNoRows {
+			respondWithError(w, r, errNotRestorable)
 			return
 		}
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, r, err)
 		return
 	}
-	if userID != dbChirp.UserID {
-		respondWithError(w, 403, "Unauthorized")
+	responseChirps := []chirp{chirpFromDB(dbChirp)}
+	if err := cfg.addAuthors(r.Context(), responseChirps); err != nil {
+		respondWithError(w, r, err)
 		return
 	}
-	err = cfg.dbQueries.DeleteChirp(r.Context(), dbChirp.ID)
+	respondWithJSON(w, 200, responseChirps[0])
+}
+
+// purgeDeletedChirps hard-deletes chirps that have been in the trash for
+// longer than the configured retention. Authors can only restore chirps during
+// the shorter grace period, the rest of the retention keeps evidence for
+// moderation.
+func (cfg *apiConfig) purgeDeletedChirps(ctx context.Context) {
+	purged, err := cfg.dbQueries.PurgeDeletedChirps(ctx, sql.NullTime{
+		Time:  time.Now().UTC().Add(-cfg.chirpRetention),
+		Valid: true,
+	})
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		slog.Error("Failed to purge deleted chirps", "error", err)
 		return
 	}
-	respondWithJSON(w, 204, nil)
+	if purged > 0 {
+		slog.Info("Purged deleted chirps", "count", purged)
+	}
 }
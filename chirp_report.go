@@ -0,0 +1,115 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/validate"
+)
+
+var reportReasons = map[string]bool{
+	"spam":           true,
+	"harassment":     true,
+	"hate":           true,
+	"violence":       true,
+	"misinformation": true,
+	"other":          true,
+}
+
+type receivedReport struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+func (rr receivedReport) validate(v *validate.Validator) {
+	v.Check(validate.NotBlank(rr.Reason), "reason", "is required")
+	v.Check(reportReasons[rr.Reason], "reason", "must be one of spam, harassment, hate, violence, misinformation or other")
+	v.Check(validate.MaxLength(rr.Details, 1000), "details", "must be at most 1000 characters")
+}
+
+type report struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ChirpID    uuid.UUID  `json:"chirp_id"`
+	ReporterID uuid.UUID  `json:"reporter_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy *uuid.UUID `json:"resolved_by,omitempty"`
+}
+
+func (cfg *apiConfig) reportChirp(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	chirpID, err := parseUUIDPathValue(r, "chirpID")
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	receivedReport := receivedReport{}
+	err = decodeJSON(w, r, &receivedReport)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, r, errChirpNotFound)
+			return
+		}
+		respondWithError(w, r, err)
+		return
+	}
+	if dbChirp.HiddenAt.Valid {
+		respondWithError(w, r, errChirpNotFound)
+		return
+	}
+	reportParams := database.CreateReportParams{
+		ID:         uuid.New(),
+		CreatedAt:  time.Now().UTC(),
+		UpdatedAt:  time.Now().UTC(),
+		ChirpID:    dbChirp.ID,
+		ReporterID: userID,
+		Reason:     receivedReport.Reason,
+		Details:    receivedReport.Details,
+	}
+	savedReport, err := cfg.dbQueries.CreateReport(r.Context(), reportParams)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			respondWithError(w, r, errAlreadyReported)
+			return
+		}
+		respondWithError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 201, reportFromDB(savedReport))
+}
+
+func reportFromDB(dbReport database.Report) report {
+	responseReport := report{
+		ID:         dbReport.ID,
+		CreatedAt:  dbReport.CreatedAt,
+		UpdatedAt:  dbReport.UpdatedAt,
+		ChirpID:    dbReport.ChirpID,
+		ReporterID: dbReport.ReporterID,
+		Reason:     dbReport.Reason,
+		Details:    dbReport.Details,
+		Status:     dbReport.Status,
+	}
+	if dbReport.ResolvedAt.Valid {
+		responseReport.ResolvedAt = &dbReport.ResolvedAt.Time
+	}
+	if dbReport.ResolvedBy.Valid {
+		responseReport.ResolvedBy = &dbReport.ResolvedBy.UUID
+	}
+	return responseReport
+}
@@ -1,29 +1,158 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/auth"
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/problem"
+	"github.com/marekbrze/chirpy/internal/validate"
 )
 
-type errorResponse struct {
-	Error string `json:"error"`
+// respondWithError writes err as application/problem+json. Domain errors
+// carry their own problem, a few common errors are recognised here, and
+// anything else is a 500 whose cause is logged but not shown.
+func respondWithError(w http.ResponseWriter, r *http.Request, err error) {
+	p := toProblem(err)
+	if p.Type.Status >= 500 {
+		loggerFrom(r.Context()).Error("Request failed", "error", err)
+	}
+	if err := problem.Write(w, p.Details(r.URL.Path)); err != nil {
+		loggerFrom(r.Context()).Error("Failed to write response", "error", err)
+	}
 }
 
-func respondWithError(w http.ResponseWriter, code int, msg string) {
-	w.WriteHeader(code)
-	w.Header().Set("Content-Type", "application/json")
+func toProblem(err error) *problem.Error {
+	var p *problem.Error
+	if errors.As(err, &p) {
+		return p
+	}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return problem.New(problem.NotFound, "")
+	case database.IsUniqueViolation(err):
+		return problem.New(problem.Conflict, "")
+	}
+	return problem.Wrap(problem.Internal, err, "")
+}
+
+// maxBodyBytes is far more than any request body chirpy accepts needs.
+const maxBodyBytes = 1 << 20
+
+// validatable bodies check their own fields once they're decoded.
+type validatable interface {
+	validate(v *validate.Validator)
+}
 
-	returnError, err := json.Marshal(errorResponse{Error: msg})
+// decodeJSON reads a single JSON value from the request body into v, refusing
+// unknown fields and bodies over maxBodyBytes, then validates v. Bodies that
+// aren't JSON, don't fit v or break its rules are the client's fault.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	return decodeBody(w, r, v, true)
+}
+
+// decodeLenientJSON is decodeJSON without the unknown field check, for
+// bodies whose format someone else owns, like webhooks, which may gain fields
+// at any time.
+func decodeLenientJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	return decodeBody(w, r, v, false)
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any, strict bool) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decodeError(decoder.Decode(v)); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return problem.New(problem.MalformedRequest, "Request body must be a single JSON value")
+	}
+	body, ok := v.(validatable)
+	if !ok {
+		return nil
+	}
+	validator := &validate.Validator{}
+	body.validate(validator)
+	return validator.Err()
+}
+
+func decodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var tooLargeErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, io.EOF):
+		return problem.Wrap(problem.MalformedRequest, err, "Request body is empty")
+	case errors.As(err, &tooLargeErr):
+		return problem.Wrap(problem.ContentTooLarge, err, fmt.Sprintf("Request body must be at most %d bytes", tooLargeErr.Limit))
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return problem.Invalid(problem.FieldError{Field: typeErr.Field, Message: fmt.Sprintf("must be a %s", jsonType(typeErr.Type.Kind().String()))})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no error type for unknown fields.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return problem.Invalid(problem.FieldError{Field: field, Message: "is not allowed"})
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return problem.Wrap(problem.MalformedRequest, err, "Request body isn't valid JSON")
+	}
+	return err
+}
+
+func jsonType(kind string) string {
+	switch kind {
+	case "string":
+		return "string"
+	case "bool":
+		return "boolean"
+	case "struct", "map":
+		return "object"
+	case "slice", "array":
+		return "array"
+	}
+	return "number"
+}
+
+// optional tells a field that's missing from a JSON body apart from one
+// that's set to null.
+type optional[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+func (o *optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+// parseUUIDPathValue reads a UUID from the named path wildcard.
+func parseUUIDPathValue(r *http.Request, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(r.PathValue(name))
 	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
+		return uuid.Nil, problem.Invalid(problem.FieldError{Field: name, Message: "must be a UUID"})
 	}
-	w.Write(returnError)
+	return id, nil
 }
 
 func respondWithJSON(w http.ResponseWriter, code int, payload any) {
-	w.WriteHeader(code)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 
 	response, err := json.Marshal(payload)
 	if err != nil {
@@ -32,3 +161,45 @@ func respondWithJSON(w http.ResponseWriter, code int, payload any) {
 	}
 	w.Write(response)
 }
+
+// authenticate returns the user from the request's bearer token and adds them
+// to the request log.
+func (cfg *apiConfig) authenticate(r *http.Request) (uuid.UUID, error) {
+	user, err := cfg.authenticateUser(r)
+	return user.ID, err
+}
+
+// authenticateUser also loads the account. Access tokens stay valid after a
+// suspension or a deletion request, so the account is checked every time.
+func (cfg *apiConfig) authenticateUser(r *http.Request) (database.User, error) {
+	headerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return database.User{}, problem.Wrap(problem.Unauthorized, err, "Missing access token")
+	}
+	userID, err := auth.ValidateJWT(headerToken, cfg.jwtSecret)
+	if err != nil {
+		return database.User{}, problem.Wrap(problem.Unauthorized, err, "Invalid access token")
+	}
+	setRequestUser(r.Context(), userID)
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err == sql.ErrNoRows {
+		return database.User{}, errUnauthorized
+	}
+	if err != nil {
+		return database.User{}, err
+	}
+	if user.SuspendedAt.Valid {
+		return database.User{}, errAccountSuspended
+	}
+	if user.DeletedAt.Valid {
+		return database.User{}, errAccountDeleted
+	}
+	return user, nil
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"github.com/marekbrze/chirpy/internal/auth"
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/problem"
+)
+
+// The dashboard is for browsers, so instead of a bearer token it keeps the
+// admin's JWT in a cookie. Every form carries a token signed from a second,
+// random cookie, which a cross-site form can't read or forge.
+const (
+	sessionCookie = "chirpy_admin_session"
+	csrfCookie    = "chirpy_csrf"
+	csrfField     = "csrf_token"
+)
+
+const (
+	chirpVolumeDays   = 14
+	recentSignups     = 10
+	dashboardReports  = 20
+	chirpChartBarSlot = 10
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+var dashboardTemplates = map[string]*template.Template{
+	"login":     parseDashboardTemplate("login.html"),
+	"dashboard": parseDashboardTemplate("dashboard.html"),
+}
+
+func parseDashboardTemplate(page string) *template.Template {
+	return template.Must(template.ParseFS(dashboardFiles, "dashboard/layout.html", "dashboard/"+page))
+}
+
+func dashboardAssets() http.Handler {
+	assets, err := fs.Sub(dashboardFiles, "dashboard/assets")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/admin/assets/", http.FileServerFS(assets))
+}
+
+type loginPage struct {
+	CSRFToken string
+	Email     string
+	Error     string
+}
+
+type dashboardPage struct {
+	CSRFToken        string
+	Admin            string
+	GeneratedAt      time.Time
+	Hits             int32
+	ChirpsCreated    float64
+	RequestsInFlight float64
+	Logins           map[string]float64
+	WebhookFailures  map[string]float64
+	ChirpVolume      []chirpVolume
+	ChirpChartWidth  int
+	RecentUsers      []userDetails
+	OpenReports      []dashboardReport
+	MoreReports      int
+}
+
+// chirpVolume is one bar of the chirps per day chart, positioned in a 100
+// unit high SVG.
+type chirpVolume struct {
+	Day    time.Time
+	Count  int64
+	X      int
+	Y      int
+	Height int
+}
+
+type dashboardReport struct {
+	report
+	ChirpBody string
+}
+
+func (cfg *apiConfig) dashboard(w http.ResponseWriter, r *http.Request) {
+	admin, err := cfg.dashboardAdmin(r)
+	if err != nil {
+		cfg.respondWithDashboardError(w, r, err)
+		return
+	}
+	token, err := cfg.csrfToken(w, r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	page, err := cfg.loadDashboard(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	page.CSRFToken = token
+	page.Admin = admin.Email
+	renderDashboard(w, r, 200, "dashboard", page)
+}
+
+func (cfg *apiConfig) loadDashboard(r *http.Request) (dashboardPage, error) {
+	page := dashboardPage{
+		GeneratedAt:     time.Now().UTC(),
+		Hits:            cfg.fileserverhits.Load(),
+		WebhookFailures: map[string]float64{},
+		ChirpChartWidth: chirpVolumeDays * chirpChartBarSlot,
+	}
+	chirpsCreated, err := cfg.metrics.values("chirpy_chirps_created_total")
+	if err != nil {
+		return dashboardPage{}, err
+	}
+	page.ChirpsCreated = chirpsCreated[""]
+	inFlight, err := cfg.metrics.values("chirpy_http_requests_in_flight")
+	if err != nil {
+		return dashboardPage{}, err
+	}
+	page.RequestsInFlight = inFlight[""]
+	page.Logins, err = cfg.metrics.values("chirpy_logins_total")
+	if err != nil {
+		return dashboardPage{}, err
+	}
+	webhooks, err := cfg.metrics.values("chirpy_webhooks_total")
+	if err != nil {
+		return dashboardPage{}, err
+	}
+	for outcome, count := range webhooks {
+		if outcome != "upgraded" && outcome != "ignored" {
+			page.WebhookFailures[outcome] = count
+		}
+	}
+
+	page.ChirpVolume, err = cfg.loadChirpVolume(r)
+	if err != nil {
+		return dashboardPage{}, err
+	}
+
+	dbUsers, err := cfg.dbQueries.GetRecentUsers(r.Context(), recentSignups)
+	if err != nil {
+		return dashboardPage{}, err
+	}
+	for _, dbUser := range dbUsers {
+		page.RecentUsers = append(page.RecentUsers, userDetailsFromDB(dbUser))
+	}
+
+	dbReports, err := cfg.dbQueries.GetOpenReportsWithChirps(r.Context())
+	if err != nil {
+		return dashboardPage{}, err
+	}
+	if len(dbReports) > dashboardReports {
+		page.MoreReports = len(dbReports) - dashboardReports
+		dbReports = dbReports[:dashboardReports]
+	}
+	for _, dbReport := range dbReports {
+		page.OpenReports = append(page.OpenReports, dashboardReport{report: reportFromDB(dbReport.Report), ChirpBody: dbReport.ChirpBody})
+	}
+	return page, nil
+}
+
+// loadChirpVolume fills in the days without chirps, which the query leaves
+// out, and scales the bars to the busiest day.
+func (cfg *apiConfig) loadChirpVolume(r *http.Request) ([]chirpVolume, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	first := today.AddDate(0, 0, 1-chirpVolumeDays)
+	rows, err := cfg.dbQueries.GetChirpCountsByDay(r.Context(), first)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[time.Time]int64{}
+	busiest := int64(1)
+	for _, row := range rows {
+		counts[row.Day.UTC()] = row.Count
+		busiest = max(busiest, row.Count)
+	}
+	volume := []chirpVolume{}
+	for i := range chirpVolumeDays {
+		day := first.AddDate(0, 0, i)
+		height := int(counts[day] * 100 / busiest)
+		volume = append(volume, chirpVolume{
+			Day:    day,
+			Count:  counts[day],
+			X:      i * chirpChartBarSlot,
+			Y:      100 - height,
+			Height: height,
+		})
+	}
+	return volume, nil
+}
+
+func (cfg *apiConfig) dashboardLoginForm(w http.ResponseWriter, r *http.Request) {
+	token, err := cfg.csrfToken(w, r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	renderDashboard(w, r, 200, "login", loginPage{CSRFToken: token})
+}
+
+// dashboardLogin is the form version of loginUser for admins. Accounts that
+// need a new password have to choose it through the API first.
+func (cfg *apiConfig) dashboardLogin(w http.ResponseWriter, r *http.Request) {
+	if err := cfg.checkCSRF(w, r); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	email := r.PostFormValue("email")
+	user, err := cfg.dbQueries.GetUser(r.Context(), email)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, r, err)
+		return
+	}
+	switch {
+	case err == sql.ErrNoRows:
+		err = errIncorrectLogin
+	case !checkPassword(r.PostFormValue("password"), user.HashedPassword):
+		err = errIncorrectLogin
+	case user.SuspendedAt.Valid:
+		err = errAccountSuspended
+	case user.DeletedAt.Valid:
+		err = errAccountDeleted
+	case user.PasswordResetRequired:
+		err = errPasswordResetRequired
+	case !user.IsAdmin:
+		err = errForbidden
+	}
+	if err != nil {
+		cfg.metrics.logins.WithLabelValues("failure").Inc()
+		cfg.auditFailedLogin(r, email, user.ID, err)
+		token, tokenErr := cfg.csrfToken(w, r)
+		if tokenErr != nil {
+			respondWithError(w, r, tokenErr)
+			return
+		}
+		message := toProblem(err).Detail
+		switch err {
+		case errPasswordResetRequired:
+			message = "A new password is required, choose one by logging in through the API"
+		case errAccountDeleted:
+			message = "Account is scheduled for deletion, log in through the API to cancel it"
+		}
+		renderDashboard(w, r, toProblem(err).Type.Status, "login", loginPage{
+			CSRFToken: token,
+			Email:     email,
+			Error:     message,
+		})
+		return
+	}
+	session, err := auth.MakeJWT(user.ID, cfg.jwtSecret, cfg.accessTokenTTL)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	err = audit(r.Context(), cfg.dbQueries, r, auditLogin, user.ID, user.ID, map[string]any{"dashboard": true})
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	cfg.metrics.logins.WithLabelValues("success").Inc()
+	http.SetCookie(w, cfg.dashboardCookie(sessionCookie, session, cfg.accessTokenTTL))
+	http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+}
+
+func checkPassword(password, hash string) bool {
+	correct, err := auth.CheckPasswordHash(password, hash)
+	return err == nil && correct
+}
+
+func (cfg *apiConfig) dashboardLogout(w http.ResponseWriter, r *http.Request) {
+	if err := cfg.checkCSRF(w, r); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	http.SetCookie(w, cfg.dashboardCookie(sessionCookie, "", -1))
+	http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+}
+
+func (cfg *apiConfig) dashboardDismissReport(w http.ResponseWriter, r *http.Request) {
+	cfg.dashboardForm(w, r, func(admin database.User) error {
+		reportID, err := parseUUIDPathValue(r, "reportID")
+		if err != nil {
+			return err
+		}
+		_, err = cfg.dismissReportAs(r, admin, reportID)
+		if err == sql.ErrNoRows {
+			return errReportNotFound
+		}
+		return err
+	})
+}
+
+func (cfg *apiConfig) dashboardHideChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.dashboardForm(w, r, func(admin database.User) error {
+		chirpID, err := parseUUIDPathValue(r, "chirpID")
+		if err != nil {
+			return err
+		}
+		_, err = cfg.changeChirpHidden(r, admin, chirpID, true)
+		if err == sql.ErrNoRows {
+			return errChirpNotFound
+		}
+		return err
+	})
+}
+
+// dashboardForm checks the session and CSRF token of a posted form, runs
+// action and sends the admin back to the dashboard.
+func (cfg *apiConfig) dashboardForm(w http.ResponseWriter, r *http.Request, action func(admin database.User) error) {
+	admin, err := cfg.dashboardAdmin(r)
+	if err != nil {
+		cfg.respondWithDashboardError(w, r, err)
+		return
+	}
+	if err := cfg.checkCSRF(w, r); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	if err := action(admin); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+}
+
+// dashboardAdmin is authenticateAdmin for the session cookie.
+func (cfg *apiConfig) dashboardAdmin(r *http.Request) (database.User, error) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return database.User{}, errUnauthorized
+	}
+	userID, err := auth.ValidateJWT(cookie.Value, cfg.jwtSecret)
+	if err != nil {
+		return database.User{}, errUnauthorized
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err == sql.ErrNoRows {
+		return database.User{}, errUnauthorized
+	}
+	if err != nil {
+		return database.User{}, err
+	}
+	if !user.IsAdmin || user.SuspendedAt.Valid || user.DeletedAt.Valid {
+		return database.User{}, errUnauthorized
+	}
+	return user, nil
+}
+
+// respondWithDashboardError sends anyone without a session to the login
+// form instead of showing them a problem.
+func (cfg *apiConfig) respondWithDashboardError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errUnauthorized) {
+		http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+		return
+	}
+	respondWithError(w, r, err)
+}
+
+// dashboardCookie is only sent back to the dashboard, never to scripts, and
+// outside the dev platform only over HTTPS. A zero maxAge makes it last until
+// the browser closes and a negative one deletes it.
+func (cfg *apiConfig) dashboardCookie(name, value string, maxAge time.Duration) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/admin",
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   cfg.platform != "dev",
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+// csrfToken returns the token for the browser's forms, giving the browser a
+// CSRF cookie first if it doesn't have one.
+func (cfg *apiConfig) csrfToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if cookie, err := r.Cookie(csrfCookie); err == nil && cookie.Value != "" {
+		return auth.MakeCSRFToken(cookie.Value, cfg.jwtSecret), nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	value := hex.EncodeToString(key)
+	http.SetCookie(w, cfg.dashboardCookie(csrfCookie, value, 0))
+	return auth.MakeCSRFToken(value, cfg.jwtSecret), nil
+}
+
+func (cfg *apiConfig) checkCSRF(w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil || !auth.CheckCSRFToken(r.PostFormValue(csrfField), cookie.Value, cfg.jwtSecret) {
+		return problem.New(problem.Forbidden, "Missing or invalid CSRF token, reload the page and try again")
+	}
+	return nil
+}
+
+// renderDashboard renders into a buffer first, so a broken template is still
+// reported as a problem instead of half a page.
+func renderDashboard(w http.ResponseWriter, r *http.Request, status int, page string, data any) {
+	var buf bytes.Buffer
+	if err := dashboardTemplates[page].ExecuteTemplate(&buf, "layout", data); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; form-action 'self'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		loggerFrom(r.Context()).Error("Failed to write response", "error", err)
+	}
+}
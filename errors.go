@@ -0,0 +1,24 @@
+package main
+
+import "github.com/marekbrze/chirpy/internal/problem"
+
+// Errors that handlers share. Each knows the problem it's shown as, see
+// respondWithError.
+var (
+	errUnauthorized          = problem.New(problem.Unauthorized, "Missing or invalid token")
+	errForbidden             = problem.New(problem.Forbidden, "Admins only")
+	errIncorrectLogin        = problem.New(problem.Unauthorized, "Incorrect email or password")
+	errIncorrectPassword     = problem.New(problem.Forbidden, "Current password is incorrect")
+	errAccountSuspended      = problem.New(problem.Forbidden, "Account suspended")
+	errAccountDeleted        = problem.New(problem.Unauthorized, "Account is scheduled for deletion, log in again to cancel it")
+	errPasswordResetRequired = problem.New(problem.Forbidden, "A new password is required, send it as new_password")
+	errNotChirpAuthor        = problem.New(problem.Forbidden, "Only the author can do that")
+	errChirpNotFound         = problem.New(problem.NotFound, "Chirp doesn't exist")
+	errUserNotFound          = problem.New(problem.NotFound, "User doesn't exist")
+	errReportNotFound        = problem.New(problem.NotFound, "Report doesn't exist")
+	errReportResolved        = problem.New(problem.Conflict, "Report has already been resolved")
+	errEmailTaken            = problem.New(problem.Conflict, "Email is already in use")
+	errHandleTaken           = problem.New(problem.Conflict, "Handle is already taken")
+	errAlreadyReported       = problem.New(problem.Conflict, "Chirp already reported")
+	errNotRestorable         = problem.New(problem.NotFound, "No restorable chirp found")
+)
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/marekbrze/chirpy/internal/health"
+)
+
+type statusResponse struct {
+	Status string `json:"status"`
+}
+
+// liveness only reports that the process can serve requests. Dependencies
+// are checked by readinessCheck so a database outage doesn't restart us.
+func liveness(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, 200, statusResponse{Status: health.StatusOK})
+}
+
+func (cfg *apiConfig) readinessCheck(w http.ResponseWriter, r *http.Request) {
+	if cfg.draining.Load() {
+		respondWithJSON(w, 503, statusResponse{Status: "draining"})
+		return
+	}
+	report := cfg.readiness.Report(r.Context())
+	if report.Status != health.StatusOK {
+		respondWithJSON(w, 503, report)
+		return
+	}
+	respondWithJSON(w, 200, report)
+}
@@ -2,7 +2,9 @@
 package auth
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -105,3 +107,19 @@ func MakeRefreshToken() (string, error) {
 	encodedString := hex.EncodeToString(key)
 	return encodedString, nil
 }
+
+// MakeCSRFToken signs the random value a browser keeps in a cookie. Forms
+// carry the signature, so a site that can plant cookies but doesn't know the
+// secret still can't make a pair that matches.
+func MakeCSRFToken(cookieValue, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("csrf:" + cookieValue))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func CheckCSRFToken(token, cookieValue, secret string) bool {
+	if token == "" || cookieValue == "" {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(MakeCSRFToken(cookieValue, secret)))
+}
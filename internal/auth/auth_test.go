@@ -101,3 +101,28 @@ func TestValidateJwt(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckCSRFToken(t *testing.T) {
+	validtoken := MakeCSRFToken("cookie", "supersecret")
+
+	testcases := []struct {
+		name        string
+		token       string
+		cookievalue string
+		expected    bool
+	}{
+		{name: "matching token", token: validtoken, cookievalue: "cookie", expected: true},
+		{name: "other cookie", token: validtoken, cookievalue: "planted", expected: false},
+		{name: "token signed with another secret", token: MakeCSRFToken("cookie", "wrongsecret"), cookievalue: "cookie", expected: false},
+		{name: "cookie value as the token", token: "cookie", cookievalue: "cookie", expected: false},
+		{name: "no cookie", token: MakeCSRFToken("", "supersecret"), cookievalue: "", expected: false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := CheckCSRFToken(tc.token, tc.cookievalue, "supersecret"); got != tc.expected {
+				t.Errorf("expected %v, but got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+// Package config is used for loading and validating chirpy settings
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+type Config struct {
+	Addr                       string        `yaml:"addr"`
+	DatabaseURL                string        `yaml:"database_url"`
+	Platform                   string        `yaml:"platform"`
+	JWTSecret                  string        `yaml:"jwt_secret"`
+	PolkaKey                   string        `yaml:"polka_key"`
+	AccessTokenTTL             time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL            time.Duration `yaml:"refresh_token_ttl"`
+	ChirpRestoreGracePeriod    time.Duration `yaml:"chirp_restore_grace_period"`
+	ChirpRetention             time.Duration `yaml:"chirp_retention"`
+	AccountDeletionGracePeriod time.Duration `yaml:"account_deletion_grace_period"`
+	ProfanityFile              string        `yaml:"profanity_file"`
+	ProfanityReload            time.Duration `yaml:"profanity_reload"`
+	RateLimitStore             string        `yaml:"rate_limit_store"`
+	TrustedProxies             string        `yaml:"trusted_proxies"`
+	RateLimitGlobal            int           `yaml:"rate_limit_global"`
+	RateLimitSignup            int           `yaml:"rate_limit_signup"`
+	RateLimitLogin             int           `yaml:"rate_limit_login"`
+	RateLimitExport            int           `yaml:"rate_limit_export"`
+	RateLimitChirps            int           `yaml:"rate_limit_chirps"`
+	RateLimitRefresh           int           `yaml:"rate_limit_refresh"`
+	RateLimitReports           int           `yaml:"rate_limit_reports"`
+	ReadHeaderTimeout          time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout                time.Duration `yaml:"read_timeout"`
+	WriteTimeout               time.Duration `yaml:"write_timeout"`
+	IdleTimeout                time.Duration `yaml:"idle_timeout"`
+	ShutdownDelay              time.Duration `yaml:"shutdown_delay"`
+	ShutdownTimeout            time.Duration `yaml:"shutdown_timeout"`
+	HealthCheckTimeout         time.Duration `yaml:"health_check_timeout"`
+	HealthCacheTTL             time.Duration `yaml:"health_cache_ttl"`
+	LogFormat                  string        `yaml:"log_format"`
+	LogLevel                   string        `yaml:"log_level"`
+	TraceExporter              string        `yaml:"trace_exporter"`
+	ServiceName                string        `yaml:"service_name"`
+	AutoMigrate                bool          `yaml:"auto_migrate"`
+	FixturesDir                string        `yaml:"fixtures_dir"`
+
+	// PrintConfig is only set from the command line.
+	PrintConfig bool `yaml:"-"`
+}
+
+func Default() Config {
+	return Config{
+		Addr:                       ":8080",
+		Platform:                   "production",
+		AccessTokenTTL:             time.Hour,
+		RefreshTokenTTL:            60 * 24 * time.Hour,
+		ChirpRestoreGracePeriod:    7 * 24 * time.Hour,
+		ChirpRetention:             30 * 24 * time.Hour,
+		AccountDeletionGracePeriod: 30 * 24 * time.Hour,
+		ProfanityReload:            time.Minute,
+		RateLimitStore:             "memory",
+		RateLimitGlobal:            300,
+		RateLimitSignup:            5,
+		RateLimitLogin:             5,
+		RateLimitExport:            2,
+		RateLimitChirps:            10,
+		RateLimitRefresh:           10,
+		RateLimitReports:           5,
+		ReadHeaderTimeout:          5 * time.Second,
+		ReadTimeout:                10 * time.Second,
+		WriteTimeout:               30 * time.Second,
+		IdleTimeout:                2 * time.Minute,
+		ShutdownDelay:              5 * time.Second,
+		ShutdownTimeout:            30 * time.Second,
+		HealthCheckTimeout:         2 * time.Second,
+		HealthCacheTTL:             5 * time.Second,
+		LogFormat:                  "text",
+		LogLevel:                   "info",
+		TraceExporter:              "none",
+		ServiceName:                "chirpy",
+		FixturesDir:                "fixtures",
+	}
+}
+
+// setting describes one option and where it can be set from.
+type setting struct {
+	name      string
+	env       string
+	usage     string
+	secret    bool
+	allowZero bool
+	value     func(cfg *Config) any
+}
+
+var settings = []setting{
+	{name: "addr", env: "CHIRPY_ADDR", usage: "address to listen on", value: func(c *Config) any { return &c.Addr }},
+	{name: "database_url", env: "CHIRPY_URL", usage: "Postgres connection URL, or sqlite:path for SQLite", secret: true, value: func(c *Config) any { return &c.DatabaseURL }},
+	{name: "platform", env: "PLATFORM", usage: "dev, test, staging or production", value: func(c *Config) any { return &c.Platform }},
+	{name: "jwt_secret", env: "JWT_SECRET", usage: "secret used to sign access tokens", secret: true, value: func(c *Config) any { return &c.JWTSecret }},
+	{name: "polka_key", env: "POLKA_KEY", usage: "API key Polka uses for webhooks", secret: true, value: func(c *Config) any { return &c.PolkaKey }},
+	{name: "access_token_ttl", env: "CHIRPY_ACCESS_TOKEN_TTL", usage: "lifetime of access tokens", value: func(c *Config) any { return &c.AccessTokenTTL }},
+	{name: "refresh_token_ttl", env: "CHIRPY_REFRESH_TOKEN_TTL", usage: "lifetime of refresh tokens", value: func(c *Config) any { return &c.RefreshTokenTTL }},
+	{name: "chirp_restore_grace_period", env: "CHIRPY_CHIRP_RESTORE_GRACE_PERIOD", usage: "how long deleted chirps can be restored", value: func(c *Config) any { return &c.ChirpRestoreGracePeriod }},
+	{name: "chirp_retention", env: "CHIRPY_CHIRP_RETENTION", usage: "how long deleted chirps are kept before purging", value: func(c *Config) any { return &c.ChirpRetention }},
+	{name: "account_deletion_grace_period", env: "CHIRPY_ACCOUNT_DELETION_GRACE_PERIOD", usage: "how long deleted accounts can be recovered by logging in", value: func(c *Config) any { return &c.AccountDeletionGracePeriod }},
+	{name: "profanity_file", env: "PROFANITY_FILE", usage: "file with banned words", value: func(c *Config) any { return &c.ProfanityFile }},
+	{name: "profanity_reload", env: "CHIRPY_PROFANITY_RELOAD", usage: "how often banned words are reloaded", value: func(c *Config) any { return &c.ProfanityReload }},
+	{name: "rate_limit_store", env: "RATE_LIMIT_STORE", usage: "memory or postgres", value: func(c *Config) any { return &c.RateLimitStore }},
+	{name: "trusted_proxies", env: "CHIRPY_TRUSTED_PROXIES", usage: "comma separated CIDRs of proxies whose X-Forwarded-For is believed", value: func(c *Config) any { return &c.TrustedProxies }},
+	{name: "rate_limit_global", env: "CHIRPY_RATE_LIMIT_GLOBAL", usage: "requests per minute a client can make to the whole API", value: func(c *Config) any { return &c.RateLimitGlobal }},
+	{name: "rate_limit_signup", env: "CHIRPY_RATE_LIMIT_SIGNUP", usage: "sign ups per minute from one client", value: func(c *Config) any { return &c.RateLimitSignup }},
+	{name: "rate_limit_login", env: "CHIRPY_RATE_LIMIT_LOGIN", usage: "login attempts per minute from one client", value: func(c *Config) any { return &c.RateLimitLogin }},
+	{name: "rate_limit_export", env: "CHIRPY_RATE_LIMIT_EXPORT", usage: "data exports per minute for one user", value: func(c *Config) any { return &c.RateLimitExport }},
+	{name: "rate_limit_chirps", env: "CHIRPY_RATE_LIMIT_CHIRPS", usage: "chirps per minute from one user", value: func(c *Config) any { return &c.RateLimitChirps }},
+	{name: "rate_limit_refresh", env: "CHIRPY_RATE_LIMIT_REFRESH", usage: "token refreshes per minute from one client", value: func(c *Config) any { return &c.RateLimitRefresh }},
+	{name: "rate_limit_reports", env: "CHIRPY_RATE_LIMIT_REPORTS", usage: "chirp reports per minute from one user", value: func(c *Config) any { return &c.RateLimitReports }},
+	{name: "read_header_timeout", env: "CHIRPY_READ_HEADER_TIMEOUT", usage: "time allowed to read request headers", value: func(c *Config) any { return &c.ReadHeaderTimeout }},
+	{name: "read_timeout", env: "CHIRPY_READ_TIMEOUT", usage: "time allowed to read a whole request", value: func(c *Config) any { return &c.ReadTimeout }},
+	{name: "write_timeout", env: "CHIRPY_WRITE_TIMEOUT", usage: "time allowed to write a response", value: func(c *Config) any { return &c.WriteTimeout }},
+	{name: "idle_timeout", env: "CHIRPY_IDLE_TIMEOUT", usage: "how long idle keep-alive connections stay open", value: func(c *Config) any { return &c.IdleTimeout }},
+	{name: "shutdown_delay", env: "CHIRPY_SHUTDOWN_DELAY", usage: "how long to fail health checks before shutting down", allowZero: true, value: func(c *Config) any { return &c.ShutdownDelay }},
+	{name: "shutdown_timeout", env: "CHIRPY_SHUTDOWN_TIMEOUT", usage: "how long to wait for requests to finish on shutdown", value: func(c *Config) any { return &c.ShutdownTimeout }},
+	{name: "health_check_timeout", env: "CHIRPY_HEALTH_CHECK_TIMEOUT", usage: "how long a single readiness check may take", value: func(c *Config) any { return &c.HealthCheckTimeout }},
+	{name: "health_cache_ttl", env: "CHIRPY_HEALTH_CACHE_TTL", usage: "how long readiness results are reused", allowZero: true, value: func(c *Config) any { return &c.HealthCacheTTL }},
+	{name: "log_format", env: "CHIRPY_LOG_FORMAT", usage: "text or json", value: func(c *Config) any { return &c.LogFormat }},
+	{name: "log_level", env: "CHIRPY_LOG_LEVEL", usage: "debug, info, warn or error", value: func(c *Config) any { return &c.LogLevel }},
+	{name: "trace_exporter", env: "CHIRPY_TRACE_EXPORTER", usage: "none, stdout or otlp", value: func(c *Config) any { return &c.TraceExporter }},
+	{name: "service_name", env: "OTEL_SERVICE_NAME", usage: "service name reported in traces", value: func(c *Config) any { return &c.ServiceName }},
+	{name: "auto_migrate", env: "CHIRPY_AUTO_MIGRATE", usage: "apply pending schema migrations on start", value: func(c *Config) any { return &c.AutoMigrate }},
+	{name: "fixtures_dir", env: "CHIRPY_FIXTURES_DIR", usage: "directory of fixture sets the test API can load on dev and test platforms", value: func(c *Config) any { return &c.FixturesDir }},
+}
+
+// Load builds the configuration from defaults, then the config file, then
+// environment variables and finally command line flags, each overriding the last.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	cfg := Default()
+
+	flags := flag.NewFlagSet("chirpy", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	configFile := flags.String("config", getenv("CHIRPY_CONFIG"), "YAML config file")
+	flags.BoolVar(&cfg.PrintConfig, "print-config", false, "print the configuration with secrets redacted and exit")
+	flagValues := map[string]string{}
+	for _, s := range settings {
+		record := func(v string) error {
+			flagValues[s.name] = v
+			return nil
+		}
+		if _, ok := s.value(&cfg).(*bool); ok {
+			flags.BoolFunc(flagName(s.name), s.usage, record)
+			continue
+		}
+		flags.Func(flagName(s.name), s.usage, record)
+	}
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(&cfg, *configFile); err != nil {
+			return Config{}, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if v := getenv(s.env); v != "" {
+			if err := set(s.value(&cfg), v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+	for _, s := range settings {
+		if v, ok := flagValues[s.name]; ok {
+			if err := set(s.value(&cfg), v); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", flagName(s.name), err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// Usage describes every flag and the environment variable it overrides.
+func Usage() string {
+	var b strings.Builder
+	b.WriteString("Usage of chirpy:\n")
+	b.WriteString("  -config string\n\tYAML config file (env CHIRPY_CONFIG)\n")
+	b.WriteString("  -print-config\n\tprint the configuration with secrets redacted and exit\n")
+	for _, s := range settings {
+		fmt.Fprintf(&b, "  -%s\n\t%s (env %s)\n", flagName(s.name), s.usage, s.env)
+	}
+	return b.String()
+}
+
+func loadFile(cfg *Config, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func set(target any, v string) error {
+	switch target := target.(type) {
+	case *string:
+		*target = v
+	case *int:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*target = n
+	case *bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*target = b
+	case *time.Duration:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*target = d
+	default:
+		return fmt.Errorf("unsupported setting type %T", target)
+	}
+	return nil
+}
+
+func flagName(name string) string {
+	return strings.ReplaceAll(name, "_", "-")
+}
+
+// Validate reports every problem with the configuration at once.
+func (cfg Config) Validate() error {
+	var errs []error
+	for _, s := range settings {
+		if !s.secret {
+			continue
+		}
+		if v, ok := s.value(&cfg).(*string); ok && *v == "" {
+			errs = append(errs, fmt.Errorf("%s is required (set %s or -%s)", s.name, s.env, flagName(s.name)))
+		}
+	}
+	switch cfg.Platform {
+	case "dev", "test", "staging", "production":
+	default:
+		errs = append(errs, fmt.Errorf("platform must be dev, test, staging or production, got %q", cfg.Platform))
+	}
+	switch cfg.LogFormat {
+	case "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("log_format must be text or json, got %q", cfg.LogFormat))
+	}
+	switch cfg.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log_level must be debug, info, warn or error, got %q", cfg.LogLevel))
+	}
+	switch cfg.TraceExporter {
+	case "none", "stdout", "otlp":
+	default:
+		errs = append(errs, fmt.Errorf("trace_exporter must be none, stdout or otlp, got %q", cfg.TraceExporter))
+	}
+	switch cfg.RateLimitStore {
+	case "memory", "postgres":
+	default:
+		errs = append(errs, fmt.Errorf("rate_limit_store must be memory or postgres, got %q", cfg.RateLimitStore))
+	}
+	if _, err := cfg.TrustedProxyPrefixes(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, s := range settings {
+		switch v := s.value(&cfg).(type) {
+		case *time.Duration:
+			if *v < 0 || (*v == 0 && !s.allowZero) {
+				errs = append(errs, fmt.Errorf("%s must be positive, got %s", s.name, *v))
+			}
+		case *int:
+			if *v < 0 || (*v == 0 && !s.allowZero) {
+				errs = append(errs, fmt.Errorf("%s must be positive, got %d", s.name, *v))
+			}
+		}
+	}
+	if cfg.ChirpRetention < cfg.ChirpRestoreGracePeriod {
+		errs = append(errs, errors.New("chirp_retention must not be shorter than chirp_restore_grace_period"))
+	}
+	return errors.Join(errs...)
+}
+
+// TrustedProxyPrefixes parses trusted_proxies. It's empty unless chirpy runs
+// behind a reverse proxy, in which case clients are told apart by the
+// address the proxy forwards.
+func (cfg Config) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, cidr := range strings.Split(cfg.TrustedProxies, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("trusted_proxies must be CIDRs like 10.0.0.0/8, got %q", cidr)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// Redacted returns the configuration as YAML with secrets hidden.
+func (cfg Config) Redacted() string {
+	for _, s := range settings {
+		if !s.secret {
+			continue
+		}
+		v, ok := s.value(&cfg).(*string)
+		if !ok || *v == "" {
+			continue
+		}
+		if u, err := url.Parse(*v); err == nil && u.Scheme != "" && u.Host != "" {
+			*v = u.Redacted()
+			continue
+		}
+		*v = redacted
+	}
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
+}
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,7 +18,7 @@ INSERT INTO chirps (id, created_at, updated_at, body, user_id)
 VALUES (
     $1, $2, $3, $4, $5
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, hidden_at, deleted_at
 `
 
 type CreateChirpParams struct {
@@ -43,27 +44,58 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.HiddenAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const deleteChirp = `-- name: DeleteChirp :exec
-DELETE FROM chirps WHERE id = $1
+UPDATE chirps
+SET deleted_at = $1, updated_at = $2
+WHERE id = $3 AND deleted_at IS NULL
 `
 
-func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteChirp, id)
+type DeleteChirpParams struct {
+	DeletedAt sql.NullTime
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) DeleteChirp(ctx context.Context, arg DeleteChirpParams) error {
+	_, err := q.db.ExecContext(ctx, deleteChirp, arg.DeletedAt, arg.UpdatedAt, arg.ID)
 	return err
 }
 
 const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE (user_id = $1 OR $1 IS NULL)
-ORDER BY created_at
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.hidden_at, chirps.deleted_at FROM chirps
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN mutes
+    ON mutes.muted_id = chirps.user_id
+    AND mutes.muter_id = $1
+LEFT JOIN blocks AS blocked_by_viewer
+    ON blocked_by_viewer.blocked_id = chirps.user_id
+    AND blocked_by_viewer.blocker_id = $1
+LEFT JOIN blocks AS blocking_viewer
+    ON blocking_viewer.blocker_id = chirps.user_id
+    AND blocking_viewer.blocked_id = $1
+WHERE (chirps.user_id = $2 OR $2 IS NULL)
+AND chirps.hidden_at IS NULL
+AND chirps.deleted_at IS NULL
+AND users.deleted_at IS NULL
+AND mutes.muter_id IS NULL
+AND blocked_by_viewer.blocker_id IS NULL
+AND blocking_viewer.blocker_id IS NULL
+ORDER BY chirps.created_at
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context, userID uuid.NullUUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps, userID)
+type GetAllChirpsParams struct {
+	ViewerID uuid.NullUUID
+	UserID   uuid.NullUUID
+}
+
+func (q *Queries) GetAllChirps(ctx context.Context, arg GetAllChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getAllChirps, arg.ViewerID, arg.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -77,6 +109,8 @@ func (q *Queries) GetAllChirps(ctx context.Context, userID uuid.NullUUID) ([]Chi
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.HiddenAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -92,10 +126,12 @@ func (q *Queries) GetAllChirps(ctx context.Context, userID uuid.NullUUID) ([]Chi
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE id = $1
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.hidden_at, chirps.deleted_at FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = $1 AND chirps.deleted_at IS NULL AND users.deleted_at IS NULL
 `
 
+// Chirps by accounts pending deletion are gone as far as readers can tell.
 func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 	row := q.db.QueryRowContext(ctx, getChirp, id)
 	var i Chirp
@@ -105,6 +141,177 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.HiddenAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getChirpCountsByDay = `-- name: GetChirpCountsByDay :many
+SELECT date_trunc('day', created_at)::TIMESTAMP AS day, COUNT(*) AS count
+FROM chirps
+WHERE created_at >= $1
+GROUP BY day
+ORDER BY day
+`
+
+type GetChirpCountsByDayRow struct {
+	Day   time.Time
+	Count int64
+}
+
+// Counts every chirp created, including ones since hidden or deleted.
+func (q *Queries) GetChirpCountsByDay(ctx context.Context, since time.Time) ([]GetChirpCountsByDayRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpCountsByDay, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpCountsByDayRow
+	for rows.Next() {
+		var i GetChirpCountsByDayRow
+		if err := rows.Scan(&i.Day, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpIncludingDeleted = `-- name: GetChirpIncludingDeleted :one
+SELECT id, created_at, updated_at, body, user_id, hidden_at, deleted_at FROM chirps
+WHERE id = $1
+`
+
+func (q *Queries) GetChirpIncludingDeleted(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpIncludingDeleted, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.HiddenAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getUserChirps = `-- name: GetUserChirps :many
+SELECT id, created_at, updated_at, body, user_id, hidden_at, deleted_at FROM chirps
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetUserChirps(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getUserChirps, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.HiddenAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeDeletedChirps = `-- name: PurgeDeletedChirps :execrows
+DELETE FROM chirps
+WHERE deleted_at < $1
+`
+
+func (q *Queries) PurgeDeletedChirps(ctx context.Context, deletedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedChirps, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL, updated_at = $1
+WHERE id = $2 AND user_id = $3 AND deleted_at > $4
+RETURNING id, created_at, updated_at, body, user_id, hidden_at, deleted_at
+`
+
+type RestoreChirpParams struct {
+	UpdatedAt time.Time
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp,
+		arg.UpdatedAt,
+		arg.ID,
+		arg.UserID,
+		arg.DeletedAt,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.HiddenAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const setChirpHidden = `-- name: SetChirpHidden :one
+UPDATE chirps
+SET hidden_at = $1, updated_at = $2
+WHERE id = $3
+RETURNING id, created_at, updated_at, body, user_id, hidden_at, deleted_at
+`
+
+type SetChirpHiddenParams struct {
+	HiddenAt  sql.NullTime
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) SetChirpHidden(ctx context.Context, arg SetChirpHiddenParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpHidden, arg.HiddenAt, arg.UpdatedAt, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.HiddenAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
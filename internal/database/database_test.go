@@ -0,0 +1,57 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/database/databasetest"
+	"github.com/marekbrze/chirpy/internal/migrate"
+)
+
+// TestConformance needs a throwaway Postgres database in
+// CHIRPY_TEST_DATABASE_URL; every table in it is emptied between cases.
+func TestConformance(t *testing.T) {
+	url := os.Getenv("CHIRPY_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("CHIRPY_TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+	migrator, err := migrate.New(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("unexpected error migrating: %v", err)
+	}
+
+	databasetest.Run(t, func(t *testing.T) database.Querier {
+		return emptyDatabase(t, db)
+	})
+	databasetest.RunTransactor(t, func(t *testing.T) (database.Querier, database.Transactor) {
+		tx := database.NewTransactor(db, &sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx database.DBTX) database.Querier {
+			return database.New(tx)
+		})
+		return emptyDatabase(t, db), tx
+	})
+}
+
+func emptyDatabase(t *testing.T, db *sql.DB) *database.Queries {
+	t.Helper()
+	q := database.New(db)
+	if err := q.DeleteUsers(context.Background()); err != nil {
+		t.Fatalf("unexpected error emptying users: %v", err)
+	}
+	if err := q.DeleteStaleRateLimitBuckets(context.Background(), time.Now().Add(24*time.Hour)); err != nil {
+		t.Fatalf("unexpected error emptying rate limit buckets: %v", err)
+	}
+	return q
+}
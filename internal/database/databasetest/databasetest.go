@@ -0,0 +1,1059 @@
+// Package databasetest is used for checking that every database.Querier
+// implementation behaves like the Postgres schema
+package databasetest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+// Run runs the conformance suite. newQuerier must return an empty database
+// each time it's called.
+func Run(t *testing.T, newQuerier func(t *testing.T) database.Querier) {
+	testcases := []struct {
+		name string
+		test func(t *testing.T, q database.Querier)
+	}{
+		{name: "users", test: testUsers},
+		{name: "unique email", test: testUniqueEmail},
+		{name: "chirps", test: testChirps},
+		{name: "soft deleted chirps", test: testSoftDeletedChirps},
+		{name: "blocks and mutes", test: testBlocksAndMutes},
+		{name: "refresh tokens", test: testRefreshTokens},
+		{name: "reports", test: testReports},
+		{name: "cascades", test: testCascades},
+		{name: "rate limit buckets", test: testRateLimitBuckets},
+		{name: "profiles", test: testProfiles},
+		{name: "avatars", test: testAvatars},
+		{name: "deleted users", test: testDeletedUsers},
+		{name: "user exports", test: testUserExports},
+		{name: "admin users", test: testAdminUsers},
+		{name: "audit events", test: testAuditEvents},
+		{name: "audit log", test: testAuditLog},
+		{name: "dashboard", test: testDashboard},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, newQuerier(t))
+		})
+	}
+}
+
+// RunTransactor checks that units of work commit or roll back as a whole.
+// newDB must return an empty database and a Transactor over it.
+func RunTransactor(t *testing.T, newDB func(t *testing.T) (database.Querier, database.Transactor)) {
+	testcases := []struct {
+		name string
+		test func(t *testing.T, q database.Querier, tx database.Transactor)
+	}{
+		{name: "commit", test: testCommit},
+		{name: "rollback", test: testRollback},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, tx := newDB(t)
+			tc.test(t, q, tx)
+		})
+	}
+}
+
+// now is truncated so values survive a round trip through any backend.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+func createUser(t *testing.T, q database.Querier, email string) database.User {
+	t.Helper()
+	user, err := q.CreateUser(context.Background(), database.CreateUserParams{
+		ID:             uuid.New(),
+		CreatedAt:      now(),
+		UpdatedAt:      now(),
+		Email:          email,
+		HashedPassword: "hash",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating user: %v", err)
+	}
+	return user
+}
+
+func createChirp(t *testing.T, q database.Querier, userID uuid.UUID, createdAt time.Time) database.Chirp {
+	t.Helper()
+	chirp, err := q.CreateChirp(context.Background(), database.CreateChirpParams{
+		ID:        uuid.New(),
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+		Body:      "hello",
+		UserID:    userID,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating chirp: %v", err)
+	}
+	return chirp
+}
+
+func chirpIDs(chirps []database.Chirp) []uuid.UUID {
+	ids := []uuid.UUID{}
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+	return ids
+}
+
+func expectIDs(t *testing.T, got []uuid.UUID, expected ...uuid.UUID) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v, but got %v", expected, got)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("expected %v, but got %v", expected, got)
+		}
+	}
+}
+
+func expectNoRows(t *testing.T, err error) {
+	t.Helper()
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, but got %v", err)
+	}
+}
+
+func testUsers(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	created := createUser(t, q, "walt@example.com")
+	if _, offset := created.CreatedAt.Zone(); created.CreatedAt.IsZero() || offset != 0 {
+		t.Errorf("expected created_at in UTC, but got %v", created.CreatedAt)
+	}
+	if created.IsChirpyRed || created.IsAdmin || created.SuspendedAt.Valid {
+		t.Errorf("expected column defaults, but got %+v", created)
+	}
+
+	byEmail, err := q.GetUser(ctx, "walt@example.com")
+	if err != nil || byEmail.ID != created.ID {
+		t.Errorf("expected to find user by email, but got %+v, %v", byEmail, err)
+	}
+	byID, err := q.GetUserByID(ctx, created.ID)
+	if err != nil || byID.Email != created.Email || !byID.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("expected to find user by id, but got %+v, %v", byID, err)
+	}
+	_, err = q.GetUser(ctx, "nobody@example.com")
+	expectNoRows(t, err)
+	_, err = q.GetUserByID(ctx, uuid.New())
+	expectNoRows(t, err)
+
+	updated, err := q.UpdateUser(ctx, database.UpdateUserParams{Email: "heisenberg@example.com", HashedPassword: "new", UpdatedAt: now(), ID: created.ID})
+	if err != nil || updated.Email != "heisenberg@example.com" || updated.HashedPassword != "new" {
+		t.Errorf("expected user to be updated, but got %+v, %v", updated, err)
+	}
+	upgraded, err := q.UpgradeUser(ctx, database.UpgradeUserParams{IsChirpyRed: true, UpdatedAt: now(), ID: created.ID})
+	if err != nil || !upgraded.IsChirpyRed {
+		t.Errorf("expected user to be upgraded, but got %+v, %v", upgraded, err)
+	}
+	suspendedAt := now()
+	suspended, err := q.SetUserSuspended(ctx, database.SetUserSuspendedParams{SuspendedAt: sql.NullTime{Time: suspendedAt, Valid: true}, UpdatedAt: now(), ID: created.ID})
+	if err != nil || !suspended.SuspendedAt.Valid || !suspended.SuspendedAt.Time.Equal(suspendedAt) {
+		t.Errorf("expected user to be suspended at %v, but got %+v, %v", suspendedAt, suspended, err)
+	}
+	_, err = q.UpgradeUser(ctx, database.UpgradeUserParams{IsChirpyRed: true, UpdatedAt: now(), ID: uuid.New()})
+	expectNoRows(t, err)
+}
+
+func testUniqueEmail(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	createUser(t, q, "walt@example.com")
+	jesse := createUser(t, q, "jesse@example.com")
+
+	_, err := q.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: now(), UpdatedAt: now(), Email: "walt@example.com", HashedPassword: "hash"})
+	if !database.IsUniqueViolation(err) {
+		t.Errorf("expected a unique violation creating a duplicate email, but got %v", err)
+	}
+	_, err = q.UpdateUser(ctx, database.UpdateUserParams{Email: "walt@example.com", HashedPassword: "hash", UpdatedAt: now(), ID: jesse.ID})
+	if !database.IsUniqueViolation(err) {
+		t.Errorf("expected a unique violation taking another user's email, but got %v", err)
+	}
+	_, err = q.UpdateUser(ctx, database.UpdateUserParams{Email: "jesse@example.com", HashedPassword: "new", UpdatedAt: now(), ID: jesse.ID})
+	if err != nil {
+		t.Errorf("expected keeping your own email to work, but got %v", err)
+	}
+}
+
+func testChirps(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	walt := createUser(t, q, "walt@example.com")
+	jesse := createUser(t, q, "jesse@example.com")
+	start := now()
+	second := createChirp(t, q, walt.ID, start.Add(time.Second))
+	first := createChirp(t, q, jesse.ID, start)
+	third := createChirp(t, q, walt.ID, start.Add(2*time.Second))
+
+	_, err := q.CreateChirp(ctx, database.CreateChirpParams{ID: uuid.New(), CreatedAt: now(), UpdatedAt: now(), Body: "orphan", UserID: uuid.New()})
+	if !database.IsForeignKeyViolation(err) {
+		t.Errorf("expected a foreign key violation for a missing author, but got %v", err)
+	}
+
+	all, err := q.GetAllChirps(ctx, database.GetAllChirpsParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectIDs(t, chirpIDs(all), first.ID, second.ID, third.ID)
+
+	byWalt, err := q.GetAllChirps(ctx, database.GetAllChirpsParams{UserID: uuid.NullUUID{UUID: walt.ID, Valid: true}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectIDs(t, chirpIDs(byWalt), second.ID, third.ID)
+
+	hidden, err := q.SetChirpHidden(ctx, database.SetChirpHiddenParams{HiddenAt: sql.NullTime{Time: now(), Valid: true}, UpdatedAt: now(), ID: second.ID})
+	if err != nil || !hidden.HiddenAt.Valid {
+		t.Fatalf("expected chirp to be hidden, but got %+v, %v", hidden, err)
+	}
+	all, err = q.GetAllChirps(ctx, database.GetAllChirpsParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectIDs(t, chirpIDs(all), first.ID, third.ID)
+	if _, err := q.GetChirp(ctx, second.ID); err != nil {
+		t.Errorf("expected hidden chirp to still be readable by ID, but got %v", err)
+	}
+	_, err = q.SetChirpHidden(ctx, database.SetChirpHiddenParams{UpdatedAt: now(), ID: uuid.New()})
+	expectNoRows(t, err)
+}
+
+func testSoftDeletedChirps(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	walt := createUser(t, q, "walt@example.com")
+	jesse := createUser(t, q, "jesse@example.com")
+	chirp := createChirp(t, q, walt.ID, now())
+	deletedAt := now()
+
+	err := q.DeleteChirp(ctx, database.DeleteChirpParams{DeletedAt: sql.NullTime{Time: deletedAt, Valid: true}, UpdatedAt: now(), ID: chirp.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = q.GetChirp(ctx, chirp.ID)
+	expectNoRows(t, err)
+	deleted, err := q.GetChirpIncludingDeleted(ctx, chirp.ID)
+	if err != nil || !deleted.DeletedAt.Time.Equal(deletedAt) {
+		t.Errorf("expected deleted chirp with deleted_at %v, but got %+v, %v", deletedAt, deleted, err)
+	}
+	all, err := q.GetAllChirps(ctx, database.GetAllChirpsParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectIDs(t, chirpIDs(all))
+
+	_, err = q.RestoreChirp(ctx, database.RestoreChirpParams{UpdatedAt: now(), ID: chirp.ID, UserID: jesse.ID, DeletedAt: sql.NullTime{Time: deletedAt.Add(-time.Hour), Valid: true}})
+	expectNoRows(t, err)
+	_, err = q.RestoreChirp(ctx, database.RestoreChirpParams{UpdatedAt: now(), ID: chirp.ID, UserID: walt.ID, DeletedAt: sql.NullTime{Time: deletedAt.Add(time.Hour), Valid: true}})
+	expectNoRows(t, err)
+	restored, err := q.RestoreChirp(ctx, database.RestoreChirpParams{UpdatedAt: now(), ID: chirp.ID, UserID: walt.ID, DeletedAt: sql.NullTime{Time: deletedAt.Add(-time.Hour), Valid: true}})
+	if err != nil || restored.DeletedAt.Valid {
+		t.Fatalf("expected chirp to be restored, but got %+v, %v", restored, err)
+	}
+
+	err = q.DeleteChirp(ctx, database.DeleteChirpParams{DeletedAt: sql.NullTime{Time: deletedAt, Valid: true}, UpdatedAt: now(), ID: chirp.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kept := createChirp(t, q, walt.ID, now())
+	purged, err := q.PurgeDeletedChirps(ctx, sql.NullTime{Time: deletedAt.Add(time.Second), Valid: true})
+	if err != nil || purged != 1 {
+		t.Errorf("expected 1 chirp to be purged, but got %d, %v", purged, err)
+	}
+	_, err = q.GetChirpIncludingDeleted(ctx, chirp.ID)
+	expectNoRows(t, err)
+	if _, err := q.GetChirp(ctx, kept.ID); err != nil {
+		t.Errorf("expected chirp that wasn't deleted to survive the purge, but got %v", err)
+	}
+}
+
+func testBlocksAndMutes(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	viewer := createUser(t, q, "viewer@example.com")
+	muted := createUser(t, q, "muted@example.com")
+	blocked := createUser(t, q, "blocked@example.com")
+	blocker := createUser(t, q, "blocker@example.com")
+	start := now()
+	own := createChirp(t, q, viewer.ID, start)
+	createChirp(t, q, muted.ID, start.Add(time.Second))
+	createChirp(t, q, blocked.ID, start.Add(2*time.Second))
+	createChirp(t, q, blocker.ID, start.Add(3*time.Second))
+
+	for i := 0; i < 2; i++ {
+		if err := q.CreateMute(ctx, database.CreateMuteParams{MuterID: viewer.ID, MutedID: muted.ID, CreatedAt: now()}); err != nil {
+			t.Fatalf("unexpected error muting: %v", err)
+		}
+		if err := q.CreateBlock(ctx, database.CreateBlockParams{BlockerID: viewer.ID, BlockedID: blocked.ID, CreatedAt: now()}); err != nil {
+			t.Fatalf("unexpected error blocking: %v", err)
+		}
+		if err := q.CreateBlock(ctx, database.CreateBlockParams{BlockerID: blocker.ID, BlockedID: viewer.ID, CreatedAt: now()}); err != nil {
+			t.Fatalf("unexpected error blocking: %v", err)
+		}
+	}
+	err := q.CreateBlock(ctx, database.CreateBlockParams{BlockerID: viewer.ID, BlockedID: uuid.New(), CreatedAt: now()})
+	if !database.IsForeignKeyViolation(err) {
+		t.Errorf("expected a foreign key violation blocking a missing user, but got %v", err)
+	}
+	err = q.CreateMute(ctx, database.CreateMuteParams{MuterID: viewer.ID, MutedID: uuid.New(), CreatedAt: now()})
+	if !database.IsForeignKeyViolation(err) {
+		t.Errorf("expected a foreign key violation muting a missing user, but got %v", err)
+	}
+
+	viewerID := uuid.NullUUID{UUID: viewer.ID, Valid: true}
+	visible, err := q.GetAllChirps(ctx, database.GetAllChirpsParams{ViewerID: viewerID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectIDs(t, chirpIDs(visible), own.ID)
+	anonymous, err := q.GetAllChirps(ctx, database.GetAllChirpsParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(anonymous) != 4 {
+		t.Errorf("expected anonymous viewers to see 4 chirps, but got %d", len(anonymous))
+	}
+
+	if err := q.DeleteMute(ctx, database.DeleteMuteParams{MuterID: viewer.ID, MutedID: muted.ID}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := q.DeleteBlock(ctx, database.DeleteBlockParams{BlockerID: viewer.ID, BlockedID: blocked.ID}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	visible, err = q.GetAllChirps(ctx, database.GetAllChirpsParams{ViewerID: viewerID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(visible) != 3 {
+		t.Errorf("expected 3 chirps after unmuting and unblocking, but got %d", len(visible))
+	}
+}
+
+func testRefreshTokens(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	walt := createUser(t, q, "walt@example.com")
+	expiresAt := now().Add(time.Hour)
+	for _, token := range []string{"first", "second"} {
+		_, err := q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: token, CreatedAt: now(), UpdatedAt: now(), ExpiresAt: expiresAt, UserID: walt.ID})
+		if err != nil {
+			t.Fatalf("unexpected error creating token: %v", err)
+		}
+	}
+	_, err := q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: "orphan", CreatedAt: now(), UpdatedAt: now(), ExpiresAt: expiresAt, UserID: uuid.New()})
+	if !database.IsForeignKeyViolation(err) {
+		t.Errorf("expected a foreign key violation for a missing user, but got %v", err)
+	}
+
+	info, err := q.GetTokenInfo(ctx, "first")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.ID != walt.ID || info.Email != walt.Email || !info.ExpiresAt.Equal(expiresAt) || info.RevokedAt.Valid {
+		t.Errorf("expected token info for %s, but got %+v", walt.Email, info)
+	}
+	_, err = q.GetTokenInfo(ctx, "missing")
+	expectNoRows(t, err)
+
+	revokedAt := now()
+	if err := q.RevokeToken(ctx, database.RevokeTokenParams{RevokedAt: sql.NullTime{Time: revokedAt, Valid: true}, UpdatedAt: now(), Token: "first"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := q.RevokeUserTokens(ctx, database.RevokeUserTokensParams{RevokedAt: sql.NullTime{Time: revokedAt.Add(time.Minute), Valid: true}, UpdatedAt: now(), UserID: walt.ID}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first, err := q.GetTokenInfo(ctx, "first")
+	if err != nil || !first.RevokedAt.Time.Equal(revokedAt) {
+		t.Errorf("expected already revoked token to keep revoked_at %v, but got %+v, %v", revokedAt, first.RevokedAt, err)
+	}
+	second, err := q.GetTokenInfo(ctx, "second")
+	if err != nil || !second.RevokedAt.Valid {
+		t.Errorf("expected every token of the user to be revoked, but got %+v, %v", second.RevokedAt, err)
+	}
+}
+
+func testReports(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	author := createUser(t, q, "author@example.com")
+	reporter := createUser(t, q, "reporter@example.com")
+	admin := createUser(t, q, "admin@example.com")
+	chirp := createChirp(t, q, author.ID, now())
+	other := createChirp(t, q, author.ID, now())
+
+	params := database.CreateReportParams{ID: uuid.New(), CreatedAt: now(), UpdatedAt: now(), ChirpID: chirp.ID, ReporterID: reporter.ID, Reason: "spam"}
+	created, err := q.CreateReport(ctx, params)
+	if err != nil || created.Status != "open" || created.ResolvedAt.Valid {
+		t.Fatalf("expected an open report, but got %+v, %v", created, err)
+	}
+	params.ID = uuid.New()
+	_, err = q.CreateReport(ctx, params)
+	if !database.IsUniqueViolation(err) {
+		t.Errorf("expected a unique violation reporting a chirp twice, but got %v", err)
+	}
+	_, err = q.CreateReport(ctx, database.CreateReportParams{ID: uuid.New(), CreatedAt: now(), UpdatedAt: now(), ChirpID: uuid.New(), ReporterID: reporter.ID, Reason: "spam"})
+	if !database.IsForeignKeyViolation(err) {
+		t.Errorf("expected a foreign key violation reporting a missing chirp, but got %v", err)
+	}
+	second, err := q.CreateReport(ctx, database.CreateReportParams{ID: uuid.New(), CreatedAt: now().Add(time.Second), UpdatedAt: now(), ChirpID: other.ID, ReporterID: reporter.ID, Reason: "hate"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	open, err := q.GetOpenReports(ctx)
+	if err != nil || len(open) != 2 || open[0].ID != created.ID {
+		t.Fatalf("expected 2 open reports oldest first, but got %+v, %v", open, err)
+	}
+	withChirps, err := q.GetOpenReportsWithChirps(ctx)
+	if err != nil || len(withChirps) != 2 || withChirps[0].Report != created || withChirps[1].Report.ChirpID != other.ID || withChirps[1].ChirpBody != other.Body {
+		t.Errorf("expected 2 open reports with their chirps oldest first, but got %+v, %v", withChirps, err)
+	}
+
+	resolvedBy := uuid.NullUUID{UUID: admin.ID, Valid: true}
+	resolved, err := q.ResolveReport(ctx, database.ResolveReportParams{Status: "dismissed", ResolvedAt: sql.NullTime{Time: now(), Valid: true}, ResolvedBy: resolvedBy, UpdatedAt: now(), ID: created.ID})
+	if err != nil || resolved.Status != "dismissed" || resolved.ResolvedBy != resolvedBy {
+		t.Errorf("expected report to be dismissed, but got %+v, %v", resolved, err)
+	}
+	_, err = q.ResolveReport(ctx, database.ResolveReportParams{Status: "dismissed", UpdatedAt: now(), ID: uuid.New()})
+	expectNoRows(t, err)
+	_, err = q.ResolveReport(ctx, database.ResolveReportParams{Status: "actioned", ResolvedAt: sql.NullTime{Time: now(), Valid: true}, ResolvedBy: resolvedBy, UpdatedAt: now(), ID: created.ID})
+	expectNoRows(t, err)
+
+	err = q.ResolveChirpReports(ctx, database.ResolveChirpReportsParams{Status: "actioned", ResolvedAt: sql.NullTime{Time: now(), Valid: true}, ResolvedBy: resolvedBy, UpdatedAt: now(), ChirpID: other.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actioned, err := q.GetReport(ctx, second.ID)
+	if err != nil || actioned.Status != "actioned" {
+		t.Errorf("expected chirp reports to be actioned, but got %+v, %v", actioned, err)
+	}
+	open, err = q.GetOpenReports(ctx)
+	if err != nil || len(open) != 0 {
+		t.Errorf("expected no open reports, but got %+v, %v", open, err)
+	}
+}
+
+func testCascades(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	walt := createUser(t, q, "walt@example.com")
+	jesse := createUser(t, q, "jesse@example.com")
+	chirp := createChirp(t, q, walt.ID, now())
+	report, err := q.CreateReport(ctx, database.CreateReportParams{ID: uuid.New(), CreatedAt: now(), UpdatedAt: now(), ChirpID: chirp.ID, ReporterID: jesse.ID, Reason: "other"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = q.PurgeDeletedChirps(ctx, sql.NullTime{Time: now().Add(time.Hour), Valid: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := q.GetReport(ctx, report.ID); err != nil {
+		t.Errorf("expected report on a live chirp to survive the purge, but got %v", err)
+	}
+	err = q.DeleteChirp(ctx, database.DeleteChirpParams{DeletedAt: sql.NullTime{Time: now(), Valid: true}, UpdatedAt: now(), ID: chirp.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = q.PurgeDeletedChirps(ctx, sql.NullTime{Time: now().Add(time.Hour), Valid: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = q.GetReport(ctx, report.ID)
+	expectNoRows(t, err)
+
+	chirp = createChirp(t, q, walt.ID, now())
+	_, err = q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: "token", CreatedAt: now(), UpdatedAt: now(), ExpiresAt: now().Add(time.Hour), UserID: walt.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := q.DeleteUsers(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = q.GetChirpIncludingDeleted(ctx, chirp.ID)
+	expectNoRows(t, err)
+	_, err = q.GetTokenInfo(ctx, "token")
+	expectNoRows(t, err)
+	_, err = q.GetUserByID(ctx, jesse.ID)
+	expectNoRows(t, err)
+}
+
+func testRateLimitBuckets(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	start := now()
+	take := func(at time.Time) database.TakeRateLimitTokenRow {
+		t.Helper()
+		row, err := q.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{Key: "login:1.2.3.4", Burst: 2, Now: at, Rate: 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return row
+	}
+
+	testcases := []struct {
+		at              time.Time
+		expectedallowed bool
+	}{
+		{at: start, expectedallowed: true},
+		{at: start, expectedallowed: true},
+		{at: start, expectedallowed: false},
+		{at: start.Add(500 * time.Millisecond), expectedallowed: false},
+		{at: start.Add(1500 * time.Millisecond), expectedallowed: true},
+	}
+	for i, tc := range testcases {
+		if row := take(tc.at); row.Allowed != tc.expectedallowed {
+			t.Errorf("take %d: expected allowed %v, but got %+v", i, tc.expectedallowed, row)
+		}
+	}
+
+	if err := q.DeleteStaleRateLimitBuckets(ctx, start.Add(time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if row := take(start.Add(2 * time.Second)); !row.Allowed || row.Tokens != 1 {
+		t.Errorf("expected a fresh bucket after cleanup, but got %+v", row)
+	}
+	// Instances' clocks can disagree, so time may seem to run backwards. That
+	// refills nothing, but doesn't drain the bucket either.
+	if row := take(start); !row.Allowed || row.Tokens != 0 {
+		t.Errorf("expected the last token despite the earlier time, but got %+v", row)
+	}
+	if row := take(start); row.Allowed || row.Tokens != 0 {
+		t.Errorf("expected an empty bucket, but got %+v", row)
+	}
+}
+
+func testCommit(t *testing.T, q database.Querier, tx database.Transactor) {
+	ctx := context.Background()
+	var user database.User
+	err := tx.InTx(ctx, func(q database.Querier) error {
+		user = createUser(t, q, "walt@example.com")
+		_, err := q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+			Token:     "committed",
+			CreatedAt: now(),
+			UpdatedAt: now(),
+			ExpiresAt: now().Add(time.Hour),
+			UserID:    user.ID,
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := q.GetUserByID(ctx, user.ID); err != nil {
+		t.Errorf("expected the user to be committed, but got %v", err)
+	}
+	if _, err := q.GetTokenInfo(ctx, "committed"); err != nil {
+		t.Errorf("expected the refresh token to be committed, but got %v", err)
+	}
+}
+
+func testRollback(t *testing.T, q database.Querier, tx database.Transactor) {
+	ctx := context.Background()
+	failed := errors.New("failed")
+	var user database.User
+	err := tx.InTx(ctx, func(q database.Querier) error {
+		user = createUser(t, q, "walt@example.com")
+		return failed
+	})
+	if err != failed {
+		t.Fatalf("expected %v, but got %v", failed, err)
+	}
+	if _, err := q.GetUserByID(ctx, user.ID); err != sql.ErrNoRows {
+		t.Errorf("expected the user to be rolled back, but got %v", err)
+	}
+	createUser(t, q, "walt@example.com")
+}
+
+func testProfiles(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	walt := createUser(t, q, "walt@example.com")
+	jesse := createUser(t, q, "jesse@example.com")
+	handle := sql.NullString{String: "heisenberg", Valid: true}
+	updated, err := q.UpdateUserProfile(ctx, database.UpdateUserProfileParams{Handle: handle, DisplayName: "Heisenberg", Bio: "Say my name", UpdatedAt: now(), ID: walt.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Handle != handle || updated.DisplayName != "Heisenberg" || updated.Bio != "Say my name" || updated.Email != walt.Email {
+		t.Errorf("expected the profile to be updated, but got %+v", updated)
+	}
+	_, err = q.UpdateUserProfile(ctx, database.UpdateUserProfileParams{Handle: handle, UpdatedAt: now(), ID: jesse.ID})
+	if !database.IsUniqueViolation(err) {
+		t.Errorf("expected a unique violation for a taken handle, but got %v", err)
+	}
+	// Users without a handle don't clash with each other.
+	if _, err := q.UpdateUserProfile(ctx, database.UpdateUserProfileParams{DisplayName: "Jesse", UpdatedAt: now(), ID: jesse.ID}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	createChirp(t, q, walt.ID, now())
+	hidden := createChirp(t, q, walt.ID, now())
+	if _, err := q.SetChirpHidden(ctx, database.SetChirpHiddenParams{HiddenAt: sql.NullTime{Time: now(), Valid: true}, UpdatedAt: now(), ID: hidden.ID}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	createChirp(t, q, jesse.ID, now())
+	profile, err := q.GetUserProfile(ctx, handle)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.ID != walt.ID || profile.ChirpCount != 1 || profile.HasAvatar {
+		t.Errorf("expected walt's profile with 1 visible chirp and no avatar, but got %+v", profile)
+	}
+	_, err = q.GetUserProfile(ctx, sql.NullString{String: "pinkman", Valid: true})
+	expectNoRows(t, err)
+
+	authors, err := q.GetAuthors(ctx, []uuid.UUID{jesse.ID, walt.ID, uuid.New()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(authors) != 2 {
+		t.Fatalf("expected 2 authors, but got %+v", authors)
+	}
+	if authors[0].ID.String() > authors[1].ID.String() {
+		t.Errorf("expected authors ordered by ID, but got %+v", authors)
+	}
+	for _, author := range authors {
+		if author.ID == walt.ID && (author.Handle != handle || author.DisplayName != "Heisenberg") {
+			t.Errorf("expected walt's handle and display name, but got %+v", author)
+		}
+		if author.ID == jesse.ID && (author.Handle.Valid || author.DisplayName != "Jesse") {
+			t.Errorf("expected jesse without a handle, but got %+v", author)
+		}
+	}
+}
+
+func testAvatars(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	walt := createUser(t, q, "walt@example.com")
+	_, err := q.GetAvatar(ctx, walt.ID)
+	expectNoRows(t, err)
+	for _, data := range [][]byte{[]byte("first"), []byte("second")} {
+		err := q.UpsertAvatar(ctx, database.UpsertAvatarParams{UserID: walt.ID, ContentType: "image/png", Data: data, UpdatedAt: now()})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	avatar, err := q.GetAvatar(ctx, walt.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(avatar.Data) != "second" || avatar.ContentType != "image/png" {
+		t.Errorf("expected the second avatar, but got %q (%s)", avatar.Data, avatar.ContentType)
+	}
+	authors, err := q.GetAuthors(ctx, []uuid.UUID{walt.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(authors) != 1 || !authors[0].HasAvatar {
+		t.Errorf("expected walt to have an avatar, but got %+v", authors)
+	}
+
+	err = q.UpsertAvatar(ctx, database.UpsertAvatarParams{UserID: uuid.New(), ContentType: "image/png", Data: []byte("x"), UpdatedAt: now()})
+	if !database.IsForeignKeyViolation(err) {
+		t.Errorf("expected a foreign key violation for a missing user, but got %v", err)
+	}
+
+	deleted, err := q.DeleteAvatar(ctx, walt.ID)
+	if err != nil || deleted != 1 {
+		t.Errorf("expected to delete 1 avatar, but got %d, %v", deleted, err)
+	}
+	_, err = q.GetAvatar(ctx, walt.ID)
+	expectNoRows(t, err)
+
+	if err := q.UpsertAvatar(ctx, database.UpsertAvatarParams{UserID: walt.ID, ContentType: "image/png", Data: []byte("x"), UpdatedAt: now()}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := q.DeleteUsers(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = q.GetAvatar(ctx, walt.ID)
+	expectNoRows(t, err)
+}
+
+func testDeletedUsers(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	walt := createUser(t, q, "walt@example.com")
+	jesse := createUser(t, q, "jesse@example.com")
+	chirp := createChirp(t, q, walt.ID, now())
+	deletedAt := now()
+	handle := sql.NullString{String: "heisenberg", Valid: true}
+	_, err := q.UpdateUserProfile(ctx, database.UpdateUserProfileParams{Handle: handle, UpdatedAt: now(), ID: walt.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deleted, err := q.SetUserDeleted(ctx, database.SetUserDeletedParams{DeletedAt: sql.NullTime{Time: deletedAt, Valid: true}, UpdatedAt: now(), ID: walt.ID})
+	if err != nil || !deleted.DeletedAt.Time.Equal(deletedAt) {
+		t.Fatalf("expected user with deleted_at %v, but got %+v, %v", deletedAt, deleted, err)
+	}
+	_, err = q.GetUserProfile(ctx, handle)
+	expectNoRows(t, err)
+	_, err = q.GetChirp(ctx, chirp.ID)
+	expectNoRows(t, err)
+	all, err := q.GetAllChirps(ctx, database.GetAllChirpsParams{})
+	if err != nil || len(all) != 0 {
+		t.Errorf("expected chirps by a deleted user to be left out, but got %+v, %v", all, err)
+	}
+	ids, err := q.GetPurgeableUserIDs(ctx, sql.NullTime{Time: deletedAt.Add(-time.Second), Valid: true})
+	if err != nil || len(ids) != 0 {
+		t.Errorf("expected no users to be purgeable inside the grace period, but got %v, %v", ids, err)
+	}
+	ids, err = q.GetPurgeableUserIDs(ctx, sql.NullTime{Time: deletedAt.Add(time.Second), Valid: true})
+	if err != nil || len(ids) != 1 || ids[0] != walt.ID {
+		t.Errorf("expected walt to be purgeable, but got %v, %v", ids, err)
+	}
+	purged, err := q.PurgeDeletedUsers(ctx, sql.NullTime{Time: deletedAt.Add(-time.Second), Valid: true})
+	if err != nil || purged != 0 {
+		t.Errorf("expected no users to be purged inside the grace period, but got %d, %v", purged, err)
+	}
+	purged, err = q.PurgeDeletedUsers(ctx, sql.NullTime{})
+	if err != nil || purged != 0 {
+		t.Errorf("expected a NULL cutoff to purge nothing, but got %d, %v", purged, err)
+	}
+
+	purged, err = q.PurgeDeletedUsers(ctx, sql.NullTime{Time: deletedAt.Add(time.Second), Valid: true})
+	if err != nil || purged != 1 {
+		t.Errorf("expected 1 user to be purged, but got %d, %v", purged, err)
+	}
+	_, err = q.GetUserByID(ctx, walt.ID)
+	expectNoRows(t, err)
+	_, err = q.GetChirpIncludingDeleted(ctx, chirp.ID)
+	expectNoRows(t, err)
+	if _, err := q.GetUserByID(ctx, jesse.ID); err != nil {
+		t.Errorf("expected user who wasn't deleted to survive the purge, but got %v", err)
+	}
+
+	_, err = q.SetUserDeleted(ctx, database.SetUserDeletedParams{DeletedAt: sql.NullTime{Time: deletedAt, Valid: true}, UpdatedAt: now(), ID: jesse.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	restored, err := q.SetUserDeleted(ctx, database.SetUserDeletedParams{UpdatedAt: now(), ID: jesse.ID})
+	if err != nil || restored.DeletedAt.Valid {
+		t.Errorf("expected deletion to be cancelled, but got %+v, %v", restored, err)
+	}
+	_, err = q.SetUserDeleted(ctx, database.SetUserDeletedParams{UpdatedAt: now(), ID: walt.ID})
+	expectNoRows(t, err)
+}
+
+func testUserExports(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	walt := createUser(t, q, "walt@example.com")
+	jesse := createUser(t, q, "jesse@example.com")
+	first := createChirp(t, q, walt.ID, now().Add(-time.Minute))
+	second := createChirp(t, q, walt.ID, now())
+	theirs := createChirp(t, q, jesse.ID, now())
+	err := q.DeleteChirp(ctx, database.DeleteChirpParams{DeletedAt: sql.NullTime{Time: now(), Valid: true}, UpdatedAt: now(), ID: second.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	chirps, err := q.GetUserChirps(ctx, walt.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectIDs(t, chirpIDs(chirps), first.ID, second.ID)
+
+	_, err = q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{Token: "token", CreatedAt: now(), UpdatedAt: now(), ExpiresAt: now().Add(time.Hour), UserID: walt.ID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tokens, err := q.GetUserRefreshTokens(ctx, walt.ID)
+	if err != nil || len(tokens) != 1 || tokens[0].Token != "token" {
+		t.Errorf("expected walt's refresh token, but got %+v, %v", tokens, err)
+	}
+	tokens, err = q.GetUserRefreshTokens(ctx, jesse.ID)
+	if err != nil || len(tokens) != 0 {
+		t.Errorf("expected no refresh tokens for jesse, but got %+v, %v", tokens, err)
+	}
+
+	report, err := q.CreateReport(ctx, database.CreateReportParams{ID: uuid.New(), CreatedAt: now(), UpdatedAt: now(), ChirpID: theirs.ID, ReporterID: walt.ID, Reason: "spam"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reports, err := q.GetReportsByReporter(ctx, walt.ID)
+	if err != nil || len(reports) != 1 || reports[0].ID != report.ID {
+		t.Errorf("expected walt's report, but got %+v, %v", reports, err)
+	}
+
+	if err := q.CreateBlock(ctx, database.CreateBlockParams{BlockerID: walt.ID, BlockedID: jesse.ID, CreatedAt: now()}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := q.CreateMute(ctx, database.CreateMuteParams{MuterID: jesse.ID, MutedID: walt.ID, CreatedAt: now()}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	blocks, err := q.GetBlocksByBlocker(ctx, walt.ID)
+	if err != nil || len(blocks) != 1 || blocks[0].BlockedID != jesse.ID {
+		t.Errorf("expected walt to block jesse, but got %+v, %v", blocks, err)
+	}
+	mutes, err := q.GetMutesByMuter(ctx, walt.ID)
+	if err != nil || len(mutes) != 0 {
+		t.Errorf("expected walt to mute nobody, but got %+v, %v", mutes, err)
+	}
+	mutes, err = q.GetMutesByMuter(ctx, jesse.ID)
+	if err != nil || len(mutes) != 1 || mutes[0].MutedID != walt.ID {
+		t.Errorf("expected jesse to mute walt, but got %+v, %v", mutes, err)
+	}
+}
+
+func testAdminUsers(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	users := []database.User{}
+	for i, email := range []string{"walt@example.com", "Jesse_Pinkman@example.com", "skyler@example.org"} {
+		user, err := q.CreateUser(ctx, database.CreateUserParams{
+			ID:             uuid.New(),
+			CreatedAt:      now().Add(time.Duration(i) * time.Second),
+			UpdatedAt:      now(),
+			Email:          email,
+			HashedPassword: "hash",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error creating user: %v", err)
+		}
+		users = append(users, user)
+	}
+	walt, jesse, skyler := users[0], users[1], users[2]
+
+	userIDs := func(users []database.User, err error) []uuid.UUID {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids := []uuid.UUID{}
+		for _, user := range users {
+			ids = append(ids, user.ID)
+		}
+		return ids
+	}
+	expectIDs(t, userIDs(q.SearchUsers(ctx, database.SearchUsersParams{Query: "EXAMPLE.COM", Limit: 10})), walt.ID, jesse.ID)
+	expectIDs(t, userIDs(q.SearchUsers(ctx, database.SearchUsersParams{Query: "", Limit: 2, Offset: 1})), jesse.ID, skyler.ID)
+	expectIDs(t, userIDs(q.SearchUsers(ctx, database.SearchUsersParams{Query: "", Limit: 2, Offset: 3})))
+	expectIDs(t, userIDs(q.SearchUsers(ctx, database.SearchUsersParams{Query: `\_`, Limit: 10})), jesse.ID)
+
+	user, err := q.SetUserAdmin(ctx, database.SetUserAdminParams{IsAdmin: true, UpdatedAt: now(), ID: skyler.ID})
+	if err != nil || !user.IsAdmin {
+		t.Errorf("expected skyler to be an admin, but got %+v, %v", user, err)
+	}
+	user, err = q.SetPasswordResetRequired(ctx, database.SetPasswordResetRequiredParams{PasswordResetRequired: true, UpdatedAt: now(), ID: walt.ID})
+	if err != nil || !user.PasswordResetRequired {
+		t.Fatalf("expected a password reset to be required, but got %+v, %v", user, err)
+	}
+	user, err = q.UpdateUser(ctx, database.UpdateUserParams{Email: "heisenberg@example.com", HashedPassword: user.HashedPassword, UpdatedAt: now(), ID: walt.ID})
+	if err != nil || !user.PasswordResetRequired {
+		t.Errorf("expected changing only the email to keep the reset required, but got %+v, %v", user, err)
+	}
+	user, err = q.UpdateUser(ctx, database.UpdateUserParams{Email: user.Email, HashedPassword: "new hash", UpdatedAt: now(), ID: walt.ID})
+	if err != nil || user.PasswordResetRequired {
+		t.Errorf("expected a new password to complete the reset, but got %+v, %v", user, err)
+	}
+}
+
+func testAuditEvents(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	admin := createUser(t, q, "admin@example.com")
+	walt := createUser(t, q, "walt@example.com")
+	actions := []string{"admin.suspend_user", "admin.unsuspend_user", "admin.grant_chirpy_red"}
+	for i, action := range actions {
+		err := q.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+			ID:        uuid.New(),
+			CreatedAt: now().Add(time.Duration(i) * time.Second),
+			Action:    action,
+			ActorID:   uuid.NullUUID{UUID: admin.ID, Valid: true},
+			TargetID:  uuid.NullUUID{UUID: walt.ID, Valid: true},
+			Ip:        "192.0.2.1",
+			UserAgent: "curl/8.0",
+			Metadata:  json.RawMessage(`{"reason":"testing"}`),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	events, err := q.GetAuditEventsByTarget(ctx, database.GetAuditEventsByTargetParams{TargetID: uuid.NullUUID{UUID: walt.ID, Valid: true}, Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 2 || events[0].Action != actions[2] || events[1].Action != actions[1] {
+		t.Fatalf("expected the 2 newest events, but got %+v", events)
+	}
+	var metadata map[string]string
+	if err := json.Unmarshal(events[0].Metadata, &metadata); err != nil || metadata["reason"] != "testing" {
+		t.Errorf("expected metadata to survive the round trip, but got %s, %v", events[0].Metadata, err)
+	}
+	if events[0].ActorID.UUID != admin.ID || events[0].Ip != "192.0.2.1" || events[0].UserAgent != "curl/8.0" {
+		t.Errorf("expected the actor, IP and user agent to be stored, but got %+v", events[0])
+	}
+	events, err = q.GetAuditEventsByTarget(ctx, database.GetAuditEventsByTargetParams{TargetID: uuid.NullUUID{UUID: admin.ID, Valid: true}, Limit: 10})
+	if err != nil || len(events) != 0 {
+		t.Errorf("expected no events targeting the admin, but got %+v, %v", events, err)
+	}
+
+	event := uuid.New()
+	err = q.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+		ID:        event,
+		CreatedAt: now().Add(time.Minute),
+		Action:    "user.email_change",
+		ActorID:   uuid.NullUUID{UUID: walt.ID, Valid: true},
+		TargetID:  uuid.NullUUID{UUID: walt.ID, Valid: true},
+		Ip:        "192.0.2.1",
+		UserAgent: "curl/8.0",
+		Metadata:  json.RawMessage(`{"email":"heisenberg@example.com","previous_email":"walt@example.com"}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = q.PseudonymizeAuditEvent(ctx, database.PseudonymizeAuditEventParams{
+		ID:       event,
+		Metadata: json.RawMessage(`{"email_sha256":"a1","previous_email_sha256":"b2"}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	events, err = q.GetAuditEventsByTarget(ctx, database.GetAuditEventsByTargetParams{TargetID: uuid.NullUUID{UUID: walt.ID, Valid: true}, Limit: 1})
+	if err != nil || len(events) != 1 || events[0].ID != event {
+		t.Fatalf("expected the email change, but got %+v, %v", events, err)
+	}
+	metadata = nil
+	if err := json.Unmarshal(events[0].Metadata, &metadata); err != nil || metadata["email_sha256"] != "a1" || metadata["email"] != "" {
+		t.Errorf("expected the emails to be replaced, but got %s, %v", events[0].Metadata, err)
+	}
+	if events[0].Ip != "" || events[0].UserAgent != "" || events[0].Action != "user.email_change" {
+		t.Errorf("expected only the IP and user agent to be cleared, but got %+v", events[0])
+	}
+}
+
+func testAuditLog(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	admin := createUser(t, q, "admin@example.com")
+	walt := createUser(t, q, "walt@example.com")
+	jesse := createUser(t, q, "jesse@example.com")
+	start := now()
+	events := []struct {
+		action string
+		actor  uuid.NullUUID
+		target uuid.NullUUID
+	}{
+		{"user.login", uuid.NullUUID{UUID: walt.ID, Valid: true}, uuid.NullUUID{UUID: walt.ID, Valid: true}},
+		{"user.login", uuid.NullUUID{UUID: jesse.ID, Valid: true}, uuid.NullUUID{UUID: jesse.ID, Valid: true}},
+		{"admin.suspend_user", uuid.NullUUID{UUID: admin.ID, Valid: true}, uuid.NullUUID{UUID: walt.ID, Valid: true}},
+		{"webhook.chirpy_red_upgrade", uuid.NullUUID{}, uuid.NullUUID{UUID: jesse.ID, Valid: true}},
+	}
+	ids := []uuid.UUID{}
+	for i, event := range events {
+		id := uuid.New()
+		ids = append(ids, id)
+		err := q.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+			ID:        id,
+			CreatedAt: start.Add(time.Duration(i) * time.Second),
+			Action:    event.action,
+			ActorID:   event.actor,
+			TargetID:  event.target,
+			Metadata:  json.RawMessage(`{}`),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	auditIDs := func(events []database.AuditEvent) []uuid.UUID {
+		ids := []uuid.UUID{}
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		return ids
+	}
+
+	testcases := []struct {
+		name     string
+		arg      database.ListAuditEventsParams
+		expected []uuid.UUID
+	}{
+		{name: "no filters", arg: database.ListAuditEventsParams{Limit: 10}, expected: []uuid.UUID{ids[3], ids[2], ids[1], ids[0]}},
+		{name: "paged", arg: database.ListAuditEventsParams{Limit: 2, Offset: 1}, expected: []uuid.UUID{ids[2], ids[1]}},
+		{name: "action", arg: database.ListAuditEventsParams{Action: sql.NullString{String: "user.login", Valid: true}, Limit: 10}, expected: []uuid.UUID{ids[1], ids[0]}},
+		{name: "actor", arg: database.ListAuditEventsParams{ActorID: uuid.NullUUID{UUID: admin.ID, Valid: true}, Limit: 10}, expected: []uuid.UUID{ids[2]}},
+		{name: "target", arg: database.ListAuditEventsParams{TargetID: uuid.NullUUID{UUID: jesse.ID, Valid: true}, Limit: 10}, expected: []uuid.UUID{ids[3], ids[1]}},
+		{
+			name: "time range",
+			arg: database.ListAuditEventsParams{
+				Since: sql.NullTime{Time: start.Add(time.Second), Valid: true},
+				Until: sql.NullTime{Time: start.Add(3 * time.Second), Valid: true},
+				Limit: 10,
+			},
+			expected: []uuid.UUID{ids[2], ids[1]},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := q.ListAuditEvents(ctx, tc.arg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expectIDs(t, auditIDs(got), tc.expected...)
+		})
+	}
+
+	log, err := q.GetSecurityLog(ctx, database.GetSecurityLogParams{UserID: uuid.NullUUID{UUID: walt.ID, Valid: true}, Limit: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectIDs(t, auditIDs(log), ids[2], ids[0])
+	log, err = q.GetSecurityLog(ctx, database.GetSecurityLogParams{UserID: uuid.NullUUID{UUID: jesse.ID, Valid: true}, Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectIDs(t, auditIDs(log), ids[1])
+}
+
+func testDashboard(t *testing.T, q database.Querier) {
+	ctx := context.Background()
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	walt := createUser(t, q, "walt@example.com")
+	jesse, err := q.CreateUser(ctx, database.CreateUserParams{
+		ID:             uuid.New(),
+		CreatedAt:      now().Add(time.Second),
+		UpdatedAt:      now(),
+		Email:          "jesse@example.com",
+		HashedPassword: "hash",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	users, err := q.GetRecentUsers(ctx, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(users) != 1 || users[0].ID != jesse.ID {
+		t.Errorf("expected only the newest user, but got %+v", users)
+	}
+
+	createChirp(t, q, walt.ID, today.Add(-49*time.Hour))
+	createChirp(t, q, walt.ID, today.Add(-23*time.Hour))
+	createChirp(t, q, walt.ID, today.Add(-22*time.Hour))
+	deleted := createChirp(t, q, jesse.ID, today.Add(time.Minute))
+	err = q.DeleteChirp(ctx, database.DeleteChirpParams{
+		DeletedAt: sql.NullTime{Time: now(), Valid: true},
+		UpdatedAt: now(),
+		ID:        deleted.ID,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	counts, err := q.GetChirpCountsByDay(ctx, today.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []database.GetChirpCountsByDayRow{
+		{Day: today.Add(-24 * time.Hour), Count: 2},
+		{Day: today, Count: 1},
+	}
+	if len(counts) != len(expected) {
+		t.Fatalf("expected %+v, but got %+v", expected, counts)
+	}
+	for i := range counts {
+		if !counts[i].Day.Equal(expected[i].Day) || counts[i].Count != expected[i].Count {
+			t.Errorf("expected %+v, but got %+v", expected, counts)
+		}
+	}
+}
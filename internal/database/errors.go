@@ -0,0 +1,68 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Querier implementations that aren't backed by a SQL database wrap these, so
+// callers can check for constraint violations without knowing the backend.
+var (
+	ErrUniqueViolation     = errors.New("unique constraint violation")
+	ErrForeignKeyViolation = errors.New("foreign key constraint violation")
+)
+
+// Extended result codes of the SQLite driver, which is matched by its Code
+// method so this package doesn't depend on it.
+const (
+	sqliteBusy                 = 5
+	sqliteBusySnapshot         = 517
+	sqliteConstraintForeignKey = 787
+	sqliteConstraintPrimaryKey = 1555
+	sqliteConstraintUnique     = 2067
+)
+
+type sqliteError interface {
+	error
+	Code() int
+}
+
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var liteErr sqliteError
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqliteConstraintUnique || liteErr.Code() == sqliteConstraintPrimaryKey
+	}
+	return errors.Is(err, ErrUniqueViolation)
+}
+
+func IsForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23503"
+	}
+	var liteErr sqliteError
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqliteConstraintForeignKey
+	}
+	return errors.Is(err, ErrForeignKeyViolation)
+}
+
+// IsSerializationFailure reports errors that go away when the transaction is
+// retried: serialization failures and deadlocks in Postgres, and a busy
+// database in SQLite.
+func IsSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "40001" || pqErr.Code == "40P01"
+	}
+	var liteErr sqliteError
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqliteBusy || liteErr.Code() == sqliteBusySnapshot
+	}
+	return false
+}
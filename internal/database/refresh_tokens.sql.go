@@ -48,21 +48,22 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 }
 
 const getTokenInfo = `-- name: GetTokenInfo :one
-SELECT t1.token, t1.created_at, t1.updated_at, t1.expires_at, t1.revoked_at, t1.user_id, t2.id, t2.email
+SELECT t1.token, t1.created_at, t1.updated_at, t1.expires_at, t1.revoked_at, t1.user_id, t2.id, t2.email, t2.suspended_at
 FROM refresh_tokens AS t1
 INNER JOIN users AS t2 ON t1.user_id = t2.id
 WHERE t1.token = $1
 `
 
 type GetTokenInfoRow struct {
-	Token     string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	ExpiresAt time.Time
-	RevokedAt sql.NullTime
-	UserID    uuid.UUID
-	ID        uuid.UUID
-	Email     string
+	Token       string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	ExpiresAt   time.Time
+	RevokedAt   sql.NullTime
+	UserID      uuid.UUID
+	ID          uuid.UUID
+	Email       string
+	SuspendedAt sql.NullTime
 }
 
 func (q *Queries) GetTokenInfo(ctx context.Context, token string) (GetTokenInfoRow, error) {
@@ -77,10 +78,47 @@ func (q *Queries) GetTokenInfo(ctx context.Context, token string) (GetTokenInfoR
 		&i.UserID,
 		&i.ID,
 		&i.Email,
+		&i.SuspendedAt,
 	)
 	return i, err
 }
 
+const getUserRefreshTokens = `-- name: GetUserRefreshTokens :many
+SELECT token, created_at, updated_at, expires_at, revoked_at, user_id FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetUserRefreshTokens(ctx context.Context, userID uuid.UUID) ([]RefreshToken, error) {
+	rows, err := q.db.QueryContext(ctx, getUserRefreshTokens, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RefreshToken
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.Token,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+			&i.UserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const revokeToken = `-- name: RevokeToken :exec
 UPDATE refresh_tokens
 SET revoked_at = $1, updated_at = $2
@@ -97,3 +135,20 @@ func (q *Queries) RevokeToken(ctx context.Context, arg RevokeTokenParams) error
 	_, err := q.db.ExecContext(ctx, revokeToken, arg.RevokedAt, arg.UpdatedAt, arg.Token)
 	return err
 }
+
+const revokeUserTokens = `-- name: RevokeUserTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = $1, updated_at = $2
+WHERE user_id = $3 AND revoked_at IS NULL
+`
+
+type RevokeUserTokensParams struct {
+	RevokedAt sql.NullTime
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+}
+
+func (q *Queries) RevokeUserTokens(ctx context.Context, arg RevokeUserTokensParams) error {
+	_, err := q.db.ExecContext(ctx, revokeUserTokens, arg.RevokedAt, arg.UpdatedAt, arg.UserID)
+	return err
+}
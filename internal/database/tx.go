@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Transactor runs fn as a unit of work: every query fn makes through q is
+// committed together, or rolled back when fn returns an error. fn may run more
+// than once, so it must not have side effects outside the database.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(q Querier) error) error
+}
+
+const txAttempts = 3
+
+// SQLTransactor is the Transactor for Postgres and SQLite. It retries the
+// whole unit of work when the database reports a serialization failure.
+type SQLTransactor struct {
+	db         *sql.DB
+	opts       *sql.TxOptions
+	newQueries func(db DBTX) Querier
+}
+
+// NewTransactor takes newQueries so each backend can wrap the transaction the
+// same way it wraps the connection pool, e.g. for tracing.
+func NewTransactor(db *sql.DB, opts *sql.TxOptions, newQueries func(db DBTX) Querier) *SQLTransactor {
+	return &SQLTransactor{
+		db:         db,
+		opts:       opts,
+		newQueries: newQueries,
+	}
+}
+
+func (t *SQLTransactor) InTx(ctx context.Context, fn func(q Querier) error) error {
+	for attempt := 1; ; attempt++ {
+		err := t.run(ctx, fn)
+		if err == nil || !IsSerializationFailure(err) || attempt == txAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 10 * time.Millisecond):
+		}
+	}
+}
+
+func (t *SQLTransactor) run(ctx context.Context, fn func(q Querier) error) error {
+	tx, err := t.db.BeginTx(ctx, t.opts)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(t.newQueries(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
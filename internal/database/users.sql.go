@@ -7,9 +7,11 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -17,7 +19,7 @@ INSERT INTO users (id, created_at, updated_at, email, hashed_password)
 VALUES (
     $1, $2, $3, $4, $5
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, suspended_at, handle, display_name, bio, deleted_at, password_reset_required
 `
 
 type CreateUserParams struct {
@@ -44,6 +46,13 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.SuspendedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.DeletedAt,
+		&i.PasswordResetRequired,
 	)
 	return i, err
 }
@@ -57,8 +66,99 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 	return err
 }
 
+const getAuthors = `-- name: GetAuthors :many
+SELECT
+    users.id,
+    users.handle,
+    users.display_name,
+    EXISTS (
+        SELECT 1 FROM avatars WHERE avatars.user_id = users.id
+    ) AS has_avatar
+FROM users
+WHERE users.id = ANY($1::UUID [])
+ORDER BY users.id
+`
+
+type GetAuthorsRow struct {
+	ID          uuid.UUID
+	Handle      sql.NullString
+	DisplayName string
+	HasAvatar   bool
+}
+
+func (q *Queries) GetAuthors(ctx context.Context, ids []uuid.UUID) ([]GetAuthorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAuthors, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAuthorsRow
+	for rows.Next() {
+		var i GetAuthorsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Handle,
+			&i.DisplayName,
+			&i.HasAvatar,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentUsers = `-- name: GetRecentUsers :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, suspended_at, handle, display_name, bio, deleted_at, password_reset_required FROM users
+ORDER BY created_at DESC, id
+LIMIT $1
+`
+
+func (q *Queries) GetRecentUsers(ctx context.Context, limit int32) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentUsers, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.IsAdmin,
+			&i.SuspendedAt,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.DeletedAt,
+			&i.PasswordResetRequired,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, suspended_at, handle, display_name, bio, deleted_at, password_reset_required FROM users
 WHERE email = $1
 `
 
@@ -72,15 +172,336 @@ func (q *Queries) GetUser(ctx context.Context, email string) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.SuspendedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.DeletedAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, suspended_at, handle, display_name, bio, deleted_at, password_reset_required FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.SuspendedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.DeletedAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const getUserProfile = `-- name: GetUserProfile :one
+SELECT
+    users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red, users.is_admin, users.suspended_at, users.handle, users.display_name, users.bio, users.deleted_at, users.password_reset_required,
+    (
+        SELECT COUNT(*) FROM chirps
+        WHERE chirps.user_id = users.id
+        AND chirps.hidden_at IS NULL
+        AND chirps.deleted_at IS NULL
+    ) AS chirp_count,
+    EXISTS (
+        SELECT 1 FROM avatars WHERE avatars.user_id = users.id
+    ) AS has_avatar
+FROM users
+WHERE users.handle = $1 AND users.deleted_at IS NULL
+`
+
+type GetUserProfileRow struct {
+	ID                    uuid.UUID
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	Email                 string
+	HashedPassword        string
+	IsChirpyRed           bool
+	IsAdmin               bool
+	SuspendedAt           sql.NullTime
+	Handle                sql.NullString
+	DisplayName           string
+	Bio                   string
+	DeletedAt             sql.NullTime
+	PasswordResetRequired bool
+	ChirpCount            int64
+	HasAvatar             bool
+}
+
+func (q *Queries) GetUserProfile(ctx context.Context, handle sql.NullString) (GetUserProfileRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserProfile, handle)
+	var i GetUserProfileRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.SuspendedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.DeletedAt,
+		&i.PasswordResetRequired,
+		&i.ChirpCount,
+		&i.HasAvatar,
+	)
+	return i, err
+}
+
+const getPurgeableUserIDs = `-- name: GetPurgeableUserIDs :many
+SELECT id FROM users
+WHERE deleted_at < $1
+`
+
+func (q *Queries) GetPurgeableUserIDs(ctx context.Context, deletedAt sql.NullTime) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getPurgeableUserIDs, deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeDeletedUsers = `-- name: PurgeDeletedUsers :execrows
+DELETE FROM users
+WHERE deleted_at < $1
+`
+
+func (q *Queries) PurgeDeletedUsers(ctx context.Context, deletedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedUsers, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const searchUsers = `-- name: SearchUsers :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, suspended_at, handle, display_name, bio, deleted_at, password_reset_required FROM users
+WHERE LOWER(email) LIKE '%' || LOWER($1) || '%' ESCAPE '\'
+ORDER BY created_at, id
+LIMIT $2 OFFSET $3
+`
+
+type SearchUsersParams struct {
+	Query  string
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, searchUsers, arg.Query, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.IsAdmin,
+			&i.SuspendedAt,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.DeletedAt,
+			&i.PasswordResetRequired,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPasswordResetRequired = `-- name: SetPasswordResetRequired :one
+UPDATE users
+SET password_reset_required = $1, updated_at = $2
+WHERE id = $3
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, suspended_at, handle, display_name, bio, deleted_at, password_reset_required
+`
+
+type SetPasswordResetRequiredParams struct {
+	PasswordResetRequired bool
+	UpdatedAt             time.Time
+	ID                    uuid.UUID
+}
+
+func (q *Queries) SetPasswordResetRequired(ctx context.Context, arg SetPasswordResetRequiredParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setPasswordResetRequired, arg.PasswordResetRequired, arg.UpdatedAt, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.SuspendedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.DeletedAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const setUserAdmin = `-- name: SetUserAdmin :one
+UPDATE users
+SET is_admin = $1, updated_at = $2
+WHERE id = $3
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, suspended_at, handle, display_name, bio, deleted_at, password_reset_required
+`
+
+type SetUserAdminParams struct {
+	IsAdmin   bool
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserAdmin, arg.IsAdmin, arg.UpdatedAt, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.SuspendedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.DeletedAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const setUserDeleted = `-- name: SetUserDeleted :one
+UPDATE users
+SET deleted_at = $1, updated_at = $2
+WHERE id = $3
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, suspended_at, handle, display_name, bio, deleted_at, password_reset_required
+`
+
+type SetUserDeletedParams struct {
+	DeletedAt sql.NullTime
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) SetUserDeleted(ctx context.Context, arg SetUserDeletedParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserDeleted, arg.DeletedAt, arg.UpdatedAt, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.SuspendedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.DeletedAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const setUserSuspended = `-- name: SetUserSuspended :one
+UPDATE users
+SET suspended_at = $1, updated_at = $2
+WHERE id = $3
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, suspended_at, handle, display_name, bio, deleted_at, password_reset_required
+`
+
+type SetUserSuspendedParams struct {
+	SuspendedAt sql.NullTime
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) SetUserSuspended(ctx context.Context, arg SetUserSuspendedParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserSuspended, arg.SuspendedAt, arg.UpdatedAt, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.SuspendedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.DeletedAt,
+		&i.PasswordResetRequired,
 	)
 	return i, err
 }
 
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
-SET email = $1, hashed_password = $2, updated_at = $3
+SET
+    email = $1,
+    hashed_password = $2,
+    password_reset_required = password_reset_required AND hashed_password = $2,
+    updated_at = $3
 WHERE id = $4
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, suspended_at, handle, display_name, bio, deleted_at, password_reset_required
 `
 
 type UpdateUserParams struct {
@@ -90,6 +511,7 @@ type UpdateUserParams struct {
 	ID             uuid.UUID
 }
 
+// A forced password reset is done once the password changes.
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
 	row := q.db.QueryRowContext(ctx, updateUser,
 		arg.Email,
@@ -105,6 +527,55 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.SuspendedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.DeletedAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET handle = $1, display_name = $2, bio = $3, updated_at = $4
+WHERE id = $5
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, suspended_at, handle, display_name, bio, deleted_at, password_reset_required
+`
+
+type UpdateUserProfileParams struct {
+	Handle      sql.NullString
+	DisplayName string
+	Bio         string
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Bio,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.SuspendedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.DeletedAt,
+		&i.PasswordResetRequired,
 	)
 	return i, err
 }
@@ -113,7 +584,7 @@ const upgradeUser = `-- name: UpgradeUser :one
 UPDATE users
 SET is_chirpy_red = $1, updated_at = $2
 WHERE id = $3
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, suspended_at, handle, display_name, bio, deleted_at, password_reset_required
 `
 
 type UpgradeUserParams struct {
@@ -132,6 +603,13 @@ func (q *Queries) UpgradeUser(ctx context.Context, arg UpgradeUserParams) (User,
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.SuspendedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.DeletedAt,
+		&i.PasswordResetRequired,
 	)
 	return i, err
 }
@@ -0,0 +1,292 @@
+// Package fixtures is used for loading named data sets into a test database
+// and for taking snapshots of one
+package fixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/auth"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrNotFound    = errors.New("fixture not found")
+	ErrInvalid     = errors.New("invalid fixture")
+	ErrInvalidName = errors.New("names may only have lower case letters, digits, - and _")
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// extensions are tried in this order, so demo.json wins over demo.yaml.
+var extensions = []string{".json", ".yaml", ".yml"}
+
+// Set is everything a fixture can create. Users are referred to by email
+// everywhere else, and reports by the ID of the chirp they're about, so a
+// chirp that's reported needs an explicit id.
+type Set struct {
+	Users         []User         `json:"users,omitempty"`
+	Chirps        []Chirp        `json:"chirps,omitempty"`
+	Reports       []Report       `json:"reports,omitempty"`
+	Blocks        []Block        `json:"blocks,omitempty"`
+	Mutes         []Mute         `json:"mutes,omitempty"`
+	RefreshTokens []RefreshToken `json:"refresh_tokens,omitempty"`
+	Avatars       []Avatar       `json:"avatars,omitempty"`
+}
+
+// User takes either a plain password, which is hashed when the set is
+// prepared, or the hash itself, which is what snapshots contain.
+type User struct {
+	ID                    uuid.UUID  `json:"id"`
+	CreatedAt             *time.Time `json:"created_at,omitempty"`
+	Email                 string     `json:"email"`
+	Password              string     `json:"password,omitempty"`
+	HashedPassword        string     `json:"hashed_password,omitempty"`
+	IsAdmin               bool       `json:"is_admin,omitempty"`
+	IsChirpyRed           bool       `json:"is_chirpy_red,omitempty"`
+	Handle                string     `json:"handle,omitempty"`
+	DisplayName           string     `json:"display_name,omitempty"`
+	Bio                   string     `json:"bio,omitempty"`
+	SuspendedAt           *time.Time `json:"suspended_at,omitempty"`
+	DeletedAt             *time.Time `json:"deleted_at,omitempty"`
+	PasswordResetRequired bool       `json:"password_reset_required,omitempty"`
+}
+
+type Chirp struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	Author    string     `json:"author"`
+	Body      string     `json:"body"`
+	HiddenAt  *time.Time `json:"hidden_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type Report struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	ChirpID    uuid.UUID  `json:"chirp_id"`
+	Reporter   string     `json:"reporter"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details,omitempty"`
+	Status     string     `json:"status,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+}
+
+type Block struct {
+	Blocker   string     `json:"blocker"`
+	Blocked   string     `json:"blocked"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+type Mute struct {
+	Muter     string     `json:"muter"`
+	Muted     string     `json:"muted"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+type RefreshToken struct {
+	Token     string     `json:"token"`
+	User      string     `json:"user"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Avatar data is base64 in JSON and YAML alike.
+type Avatar struct {
+	User        string    `json:"user"`
+	ContentType string    `json:"content_type"`
+	Data        []byte    `json:"data"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Parse reads a set as JSON or YAML, going by the file name. YAML goes
+// through JSON so both formats use the same field names and the same rules
+// for times and UUIDs.
+func Parse(filename string, data []byte) (Set, error) {
+	if ext := path.Ext(filename); ext == ".yaml" || ext == ".yml" {
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return Set{}, fmt.Errorf("%w: %s: %v", ErrInvalid, filename, err)
+		}
+		var err error
+		data, err = json.Marshal(doc)
+		if err != nil {
+			return Set{}, fmt.Errorf("%w: %s: %v", ErrInvalid, filename, err)
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var set Set
+	if err := decoder.Decode(&set); err != nil {
+		return Set{}, fmt.Errorf("%w: %s: %v", ErrInvalid, filename, err)
+	}
+	return set, nil
+}
+
+// Prepare fills in IDs and times left out of the fixture and hashes plain
+// passwords. Hashing is slow, so it's done before the set is loaded rather
+// than inside a transaction.
+func (s *Set) Prepare(now time.Time) error {
+	for i := range s.Users {
+		u := &s.Users[i]
+		if u.ID == uuid.Nil {
+			u.ID = uuid.New()
+		}
+		if u.CreatedAt == nil {
+			u.CreatedAt = &now
+		}
+		u.Email = strings.TrimSpace(u.Email)
+		u.Handle = strings.ToLower(u.Handle)
+		if u.HashedPassword == "" {
+			if u.Password == "" {
+				return fmt.Errorf("%w: user %s needs a password or hashed_password", ErrInvalid, u.Email)
+			}
+			hash, err := auth.HashPassword(u.Password)
+			if err != nil {
+				return err
+			}
+			u.HashedPassword = hash
+		}
+		u.Password = ""
+	}
+	for i := range s.Chirps {
+		if s.Chirps[i].ID == uuid.Nil {
+			s.Chirps[i].ID = uuid.New()
+		}
+		if s.Chirps[i].CreatedAt == nil {
+			s.Chirps[i].CreatedAt = &now
+		}
+	}
+	for i := range s.Reports {
+		if s.Reports[i].ID == uuid.Nil {
+			s.Reports[i].ID = uuid.New()
+		}
+		if s.Reports[i].CreatedAt == nil {
+			s.Reports[i].CreatedAt = &now
+		}
+	}
+	for i := range s.Blocks {
+		if s.Blocks[i].CreatedAt == nil {
+			s.Blocks[i].CreatedAt = &now
+		}
+	}
+	for i := range s.Mutes {
+		if s.Mutes[i].CreatedAt == nil {
+			s.Mutes[i].CreatedAt = &now
+		}
+	}
+	for i := range s.RefreshTokens {
+		t := &s.RefreshTokens[i]
+		if t.Token == "" {
+			token, err := auth.MakeRefreshToken()
+			if err != nil {
+				return err
+			}
+			t.Token = token
+		}
+		if t.CreatedAt == nil {
+			t.CreatedAt = &now
+		}
+		if t.ExpiresAt == nil {
+			expiresAt := now.Add(24 * time.Hour)
+			t.ExpiresAt = &expiresAt
+		}
+	}
+	for i := range s.Avatars {
+		if s.Avatars[i].UpdatedAt.IsZero() {
+			s.Avatars[i].UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+// Dir finds sets by name in a directory of .json, .yaml and .yml files.
+type Dir struct {
+	FS fs.FS
+}
+
+// Names lists the sets in the directory. A directory that doesn't exist has
+// none.
+func (d Dir) Names() ([]string, error) {
+	entries, err := fs.ReadDir(d.FS, ".")
+	if errors.Is(err, fs.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		name := strings.TrimSuffix(entry.Name(), ext)
+		if entry.IsDir() || !slices.Contains(extensions, ext) || !namePattern.MatchString(name) || slices.Contains(names, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (d Dir) Load(name string) (Set, error) {
+	if err := CheckName(name); err != nil {
+		return Set{}, err
+	}
+	for _, ext := range extensions {
+		data, err := fs.ReadFile(d.FS, name+ext)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Set{}, err
+		}
+		return Parse(name+ext, data)
+	}
+	return Set{}, ErrNotFound
+}
+
+// CheckName keeps names usable as file names without escaping the directory.
+func CheckName(name string) error {
+	if !namePattern.MatchString(name) {
+		return ErrInvalidName
+	}
+	return nil
+}
+
+// Snapshots keeps snapshots in memory. They're meant to last for a test run,
+// not across restarts; fetch one and save it as a fixture to keep it.
+type Snapshots struct {
+	mu  sync.Mutex
+	set map[string]Set
+}
+
+func NewSnapshots() *Snapshots {
+	return &Snapshots{set: map[string]Set{}}
+}
+
+func (s *Snapshots) Save(name string, set Set) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set[name] = set
+}
+
+func (s *Snapshots) Get(name string) (Set, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	set, ok := s.set[name]
+	if !ok {
+		return Set{}, ErrNotFound
+	}
+	return set, nil
+}
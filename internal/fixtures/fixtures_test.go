@@ -0,0 +1,151 @@
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/auth"
+	"github.com/marekbrze/chirpy/internal/memdb"
+)
+
+const demo = `
+users:
+  - email: walt@example.com
+    password: hunter2
+    is_admin: true
+    handle: Heisenberg
+  - email: jesse@example.com
+    password: hunter2
+    suspended_at: 2024-05-01T12:00:00Z
+chirps:
+  - id: 6c1f3f1e-6f8e-4a5e-9a53-0d2a4b7e9d10
+    author: jesse@example.com
+    body: Yeah, science!
+    hidden_at: 2024-05-02T12:00:00Z
+reports:
+  - chirp_id: 6c1f3f1e-6f8e-4a5e-9a53-0d2a4b7e9d10
+    reporter: walt@example.com
+    reason: spam
+    status: actioned
+    resolved_by: walt@example.com
+blocks:
+  - blocker: walt@example.com
+    blocked: jesse@example.com
+refresh_tokens:
+  - user: walt@example.com
+`
+
+func TestDir(t *testing.T) {
+	dir := Dir{FS: fstest.MapFS{
+		"demo.yaml":    {Data: []byte(demo)},
+		"empty.json":   {Data: []byte(`{}`)},
+		"broken.json":  {Data: []byte(`{"users": [{"emial": "walt@example.com"}]}`)},
+		"notes.txt":    {Data: []byte("not a fixture")},
+		"Capitals.yml": {Data: []byte("{}")},
+	}}
+	names, err := dir.Names()
+	if err != nil || !reflect.DeepEqual(names, []string{"broken", "demo", "empty"}) {
+		t.Errorf("expected the three valid names, but got %v, %v", names, err)
+	}
+	if set, err := dir.Load("demo"); err != nil || len(set.Users) != 2 || set.Chirps[0].HiddenAt == nil {
+		t.Errorf("expected the demo set, but got %+v, %v", set, err)
+	}
+	if _, err := dir.Load("broken"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected unknown fields to be rejected, but got %v", err)
+	}
+	if _, err := dir.Load("missing"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, but got %v", err)
+	}
+	if _, err := dir.Load("../demo"); err != ErrInvalidName {
+		t.Errorf("expected ErrInvalidName, but got %v", err)
+	}
+	if names, err := (Dir{FS: fstest.MapFS{}}).Names(); err != nil || len(names) != 0 {
+		t.Errorf("expected no names, but got %v, %v", names, err)
+	}
+}
+
+func TestLoadAndSnapshot(t *testing.T) {
+	ctx := context.Background()
+	set, err := Parse("demo.yaml", []byte(demo))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := set.Prepare(time.Now().UTC()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db := memdb.New()
+	if err := Load(ctx, db, set); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	walt, err := db.GetUser(ctx, "walt@example.com")
+	if err != nil || !walt.IsAdmin || walt.Handle.String != "heisenberg" {
+		t.Errorf("expected walt to be an admin called heisenberg, but got %+v, %v", walt, err)
+	}
+	if ok, err := auth.CheckPasswordHash("hunter2", walt.HashedPassword); err != nil || !ok {
+		t.Errorf("expected the password to be hashed, but got %v, %v", ok, err)
+	}
+
+	snapshot, err := Snapshot(ctx, db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(snapshot.Users) != 2 || len(snapshot.Chirps) != 1 || len(snapshot.Reports) != 1 || len(snapshot.Blocks) != 1 || len(snapshot.RefreshTokens) != 1 {
+		t.Fatalf("expected everything in the snapshot, but got %+v", snapshot)
+	}
+	if r := snapshot.Reports[0]; r.Status != "actioned" || r.ResolvedBy != "walt@example.com" {
+		t.Errorf("expected the report to stay resolved by walt, but got %+v", r)
+	}
+	restored := memdb.New()
+	if err := Load(ctx, restored, snapshot); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, err := Snapshot(ctx, restored)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(snapshot, again) {
+		t.Errorf("expected restoring a snapshot to give the same data, but got\n%+v\nwant\n%+v", again, snapshot)
+	}
+}
+
+func TestLoadUnknownUser(t *testing.T) {
+	set := Set{Chirps: []Chirp{{Author: "nobody@example.com", Body: "hi"}}}
+	if err := set.Prepare(time.Now().UTC()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Load(context.Background(), memdb.New(), set); !errors.Is(err, ErrUnknownUser) {
+		t.Errorf("expected ErrUnknownUser, but got %v", err)
+	}
+	set = Set{Users: []User{{ID: uuid.New(), Email: "walt@example.com"}}}
+	if err := set.Prepare(time.Now().UTC()); !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected a user without a password to be invalid, but got %v", err)
+	}
+}
+
+// The sets in the repository's fixtures directory must keep loading as the
+// schema changes.
+func TestRepositoryFixtures(t *testing.T) {
+	dir := Dir{FS: os.DirFS("../../fixtures")}
+	names, err := dir.Names()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range names {
+		set, err := dir.Load(name)
+		if err == nil {
+			err = set.Prepare(time.Now().UTC())
+		}
+		if err == nil {
+			err = Load(context.Background(), memdb.New(), set)
+		}
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
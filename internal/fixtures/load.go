@@ -0,0 +1,350 @@
+package fixtures
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+var ErrUnknownUser = errors.New("no user with that email")
+
+// Load creates everything in the set, which must have been prepared. Users
+// can refer to each other within the set or to users already in the
+// database. Run it in a transaction so a bad set leaves nothing behind.
+func Load(ctx context.Context, q database.Querier, s Set) error {
+	ids := map[string]uuid.UUID{}
+	for _, u := range s.Users {
+		if err := loadUser(ctx, q, u); err != nil {
+			return err
+		}
+		ids[u.Email] = u.ID
+	}
+	userID := func(email string) (uuid.UUID, error) {
+		if id, ok := ids[email]; ok {
+			return id, nil
+		}
+		user, err := q.GetUser(ctx, email)
+		if err == sql.ErrNoRows {
+			return uuid.Nil, fmt.Errorf("%w: %q", ErrUnknownUser, email)
+		}
+		if err != nil {
+			return uuid.Nil, err
+		}
+		ids[email] = user.ID
+		return user.ID, nil
+	}
+
+	for _, c := range s.Chirps {
+		authorID, err := userID(c.Author)
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateChirp(ctx, database.CreateChirpParams{
+			ID:        c.ID,
+			CreatedAt: *c.CreatedAt,
+			UpdatedAt: *c.CreatedAt,
+			Body:      c.Body,
+			UserID:    authorID,
+		})
+		if err != nil {
+			return err
+		}
+		if c.HiddenAt != nil {
+			_, err := q.SetChirpHidden(ctx, database.SetChirpHiddenParams{HiddenAt: nullTime(c.HiddenAt), UpdatedAt: *c.HiddenAt, ID: c.ID})
+			if err != nil {
+				return err
+			}
+		}
+		if c.DeletedAt != nil {
+			err := q.DeleteChirp(ctx, database.DeleteChirpParams{DeletedAt: nullTime(c.DeletedAt), UpdatedAt: *c.DeletedAt, ID: c.ID})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, r := range s.Reports {
+		reporterID, err := userID(r.Reporter)
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateReport(ctx, database.CreateReportParams{
+			ID:         r.ID,
+			CreatedAt:  *r.CreatedAt,
+			UpdatedAt:  *r.CreatedAt,
+			ChirpID:    r.ChirpID,
+			ReporterID: reporterID,
+			Reason:     r.Reason,
+			Details:    r.Details,
+		})
+		if err != nil {
+			return err
+		}
+		if r.Status == "" || r.Status == "open" {
+			continue
+		}
+		resolvedBy := uuid.NullUUID{}
+		if r.ResolvedBy != "" {
+			resolvedBy.UUID, err = userID(r.ResolvedBy)
+			if err != nil {
+				return err
+			}
+			resolvedBy.Valid = true
+		}
+		resolvedAt := nullTime(r.ResolvedAt)
+		if !resolvedAt.Valid {
+			resolvedAt = sql.NullTime{Time: *r.CreatedAt, Valid: true}
+		}
+		_, err = q.ResolveReport(ctx, database.ResolveReportParams{
+			Status:     r.Status,
+			ResolvedAt: resolvedAt,
+			ResolvedBy: resolvedBy,
+			UpdatedAt:  resolvedAt.Time,
+			ID:         r.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, b := range s.Blocks {
+		blockerID, err := userID(b.Blocker)
+		if err != nil {
+			return err
+		}
+		blockedID, err := userID(b.Blocked)
+		if err != nil {
+			return err
+		}
+		err = q.CreateBlock(ctx, database.CreateBlockParams{BlockerID: blockerID, BlockedID: blockedID, CreatedAt: *b.CreatedAt})
+		if err != nil {
+			return err
+		}
+	}
+	for _, m := range s.Mutes {
+		muterID, err := userID(m.Muter)
+		if err != nil {
+			return err
+		}
+		mutedID, err := userID(m.Muted)
+		if err != nil {
+			return err
+		}
+		err = q.CreateMute(ctx, database.CreateMuteParams{MuterID: muterID, MutedID: mutedID, CreatedAt: *m.CreatedAt})
+		if err != nil {
+			return err
+		}
+	}
+	for _, t := range s.RefreshTokens {
+		ownerID, err := userID(t.User)
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+			Token:     t.Token,
+			CreatedAt: *t.CreatedAt,
+			UpdatedAt: *t.CreatedAt,
+			ExpiresAt: *t.ExpiresAt,
+			UserID:    ownerID,
+		})
+		if err != nil {
+			return err
+		}
+		if t.RevokedAt != nil {
+			err := q.RevokeToken(ctx, database.RevokeTokenParams{RevokedAt: nullTime(t.RevokedAt), UpdatedAt: *t.RevokedAt, Token: t.Token})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, a := range s.Avatars {
+		ownerID, err := userID(a.User)
+		if err != nil {
+			return err
+		}
+		err = q.UpsertAvatar(ctx, database.UpsertAvatarParams{UserID: ownerID, ContentType: a.ContentType, Data: a.Data, UpdatedAt: a.UpdatedAt})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadUser creates the user and then applies the account state that
+// CreateUser doesn't take, one query per flag, the way the API would.
+func loadUser(ctx context.Context, q database.Querier, u User) error {
+	createdAt := *u.CreatedAt
+	_, err := q.CreateUser(ctx, database.CreateUserParams{
+		ID:             u.ID,
+		CreatedAt:      createdAt,
+		UpdatedAt:      createdAt,
+		Email:          u.Email,
+		HashedPassword: u.HashedPassword,
+	})
+	if err != nil {
+		return err
+	}
+	if u.Handle != "" || u.DisplayName != "" || u.Bio != "" {
+		_, err := q.UpdateUserProfile(ctx, database.UpdateUserProfileParams{
+			Handle:      sql.NullString{String: u.Handle, Valid: u.Handle != ""},
+			DisplayName: u.DisplayName,
+			Bio:         u.Bio,
+			UpdatedAt:   createdAt,
+			ID:          u.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if u.IsAdmin {
+		if _, err := q.SetUserAdmin(ctx, database.SetUserAdminParams{IsAdmin: true, UpdatedAt: createdAt, ID: u.ID}); err != nil {
+			return err
+		}
+	}
+	if u.IsChirpyRed {
+		if _, err := q.UpgradeUser(ctx, database.UpgradeUserParams{IsChirpyRed: true, UpdatedAt: createdAt, ID: u.ID}); err != nil {
+			return err
+		}
+	}
+	if u.PasswordResetRequired {
+		_, err := q.SetPasswordResetRequired(ctx, database.SetPasswordResetRequiredParams{PasswordResetRequired: true, UpdatedAt: createdAt, ID: u.ID})
+		if err != nil {
+			return err
+		}
+	}
+	if u.SuspendedAt != nil {
+		_, err := q.SetUserSuspended(ctx, database.SetUserSuspendedParams{SuspendedAt: nullTime(u.SuspendedAt), UpdatedAt: *u.SuspendedAt, ID: u.ID})
+		if err != nil {
+			return err
+		}
+	}
+	if u.DeletedAt != nil {
+		_, err := q.SetUserDeleted(ctx, database.SetUserDeletedParams{DeletedAt: nullTime(u.DeletedAt), UpdatedAt: *u.DeletedAt, ID: u.ID})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot reads every user and what they own into a set that Load can
+// bring back. It includes password hashes and refresh tokens, which is what
+// makes a restored database usable, and why this is only for test data.
+func Snapshot(ctx context.Context, q database.Querier) (Set, error) {
+	const pageSize = 100
+	var users []database.User
+	for offset := 0; ; offset += pageSize {
+		page, err := q.SearchUsers(ctx, database.SearchUsersParams{Limit: pageSize, Offset: int32(offset)})
+		if err != nil {
+			return Set{}, err
+		}
+		users = append(users, page...)
+		if len(page) < pageSize {
+			break
+		}
+	}
+	emails := map[uuid.UUID]string{}
+	for _, u := range users {
+		emails[u.ID] = u.Email
+	}
+
+	var s Set
+	for _, u := range users {
+		s.Users = append(s.Users, User{
+			ID:                    u.ID,
+			CreatedAt:             &u.CreatedAt,
+			Email:                 u.Email,
+			HashedPassword:        u.HashedPassword,
+			IsAdmin:               u.IsAdmin,
+			IsChirpyRed:           u.IsChirpyRed,
+			Handle:                u.Handle.String,
+			DisplayName:           u.DisplayName,
+			Bio:                   u.Bio,
+			SuspendedAt:           timePtr(u.SuspendedAt),
+			DeletedAt:             timePtr(u.DeletedAt),
+			PasswordResetRequired: u.PasswordResetRequired,
+		})
+		chirps, err := q.GetUserChirps(ctx, u.ID)
+		if err != nil {
+			return Set{}, err
+		}
+		for _, c := range chirps {
+			s.Chirps = append(s.Chirps, Chirp{
+				ID:        c.ID,
+				CreatedAt: &c.CreatedAt,
+				Author:    u.Email,
+				Body:      c.Body,
+				HiddenAt:  timePtr(c.HiddenAt),
+				DeletedAt: timePtr(c.DeletedAt),
+			})
+		}
+		reports, err := q.GetReportsByReporter(ctx, u.ID)
+		if err != nil {
+			return Set{}, err
+		}
+		for _, r := range reports {
+			s.Reports = append(s.Reports, Report{
+				ID:         r.ID,
+				CreatedAt:  &r.CreatedAt,
+				ChirpID:    r.ChirpID,
+				Reporter:   u.Email,
+				Reason:     r.Reason,
+				Details:    r.Details,
+				Status:     r.Status,
+				ResolvedAt: timePtr(r.ResolvedAt),
+				ResolvedBy: emails[r.ResolvedBy.UUID],
+			})
+		}
+		blocks, err := q.GetBlocksByBlocker(ctx, u.ID)
+		if err != nil {
+			return Set{}, err
+		}
+		for _, b := range blocks {
+			s.Blocks = append(s.Blocks, Block{Blocker: u.Email, Blocked: emails[b.BlockedID], CreatedAt: &b.CreatedAt})
+		}
+		mutes, err := q.GetMutesByMuter(ctx, u.ID)
+		if err != nil {
+			return Set{}, err
+		}
+		for _, m := range mutes {
+			s.Mutes = append(s.Mutes, Mute{Muter: u.Email, Muted: emails[m.MutedID], CreatedAt: &m.CreatedAt})
+		}
+		tokens, err := q.GetUserRefreshTokens(ctx, u.ID)
+		if err != nil {
+			return Set{}, err
+		}
+		for _, t := range tokens {
+			s.RefreshTokens = append(s.RefreshTokens, RefreshToken{
+				Token:     t.Token,
+				User:      u.Email,
+				CreatedAt: &t.CreatedAt,
+				ExpiresAt: &t.ExpiresAt,
+				RevokedAt: timePtr(t.RevokedAt),
+			})
+		}
+		avatar, err := q.GetAvatar(ctx, u.ID)
+		if err == nil {
+			s.Avatars = append(s.Avatars, Avatar{User: u.Email, ContentType: avatar.ContentType, Data: avatar.Data, UpdatedAt: avatar.UpdatedAt})
+		} else if err != sql.ErrNoRows {
+			return Set{}, err
+		}
+	}
+	return s, nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,171 @@
+// Package health is used for checking whether chirpy and its dependencies work
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type Report struct {
+	Status    string                 `json:"status"`
+	CheckedAt time.Time              `json:"checked_at"`
+	Checks    map[string]CheckResult `json:"checks"`
+}
+
+// Checker runs its checks at most once per cacheTTL, so frequent probes
+// don't hammer the database.
+type Checker struct {
+	checks   []Check
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu     sync.Mutex
+	report Report
+}
+
+func NewChecker(timeout, cacheTTL time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		checks:   checks,
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+	}
+}
+
+func (c *Checker) Report(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.report.CheckedAt.IsZero() && time.Since(c.report.CheckedAt) < c.cacheTTL {
+		return c.report
+	}
+
+	// The report is cached for other probes, so a probe that gives up
+	// mustn't fail the checks for them. The timeout still bounds them.
+	ctx = context.WithoutCancel(ctx)
+	results := make([]CheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:    StatusOK,
+		CheckedAt: time.Now().UTC(),
+		Checks:    map[string]CheckResult{},
+	}
+	for i, check := range c.checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	c.report = report
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	result := CheckResult{
+		Status:   StatusOK,
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func Ping(db *sql.DB) Check {
+	return Check{
+		Name: "database",
+		Check: func(ctx context.Context) error {
+			return db.PingContext(ctx)
+		},
+	}
+}
+
+// SchemaVersion fails when the database schema is older than the version the
+// queries were generated for. A newer schema passes so a rollback keeps serving.
+func SchemaVersion(current func(ctx context.Context) (int64, error), expected int64) Check {
+	return Check{
+		Name: "schema_version",
+		Check: func(ctx context.Context) error {
+			version, err := current(ctx)
+			if err != nil {
+				return err
+			}
+			if version < expected {
+				return fmt.Errorf("schema version is %d, expected at least %d", version, expected)
+			}
+			return nil
+		},
+	}
+}
+
+// Heartbeat is beaten by a background worker on every run and fails its check
+// when the worker hasn't run for longer than maxAge.
+type Heartbeat struct {
+	name   string
+	maxAge time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+func NewHeartbeat(name string, maxAge time.Duration) *Heartbeat {
+	return &Heartbeat{
+		name:   name,
+		maxAge: maxAge,
+		last:   time.Now(),
+	}
+}
+
+func (h *Heartbeat) Beat() {
+	h.mu.Lock()
+	h.last = time.Now()
+	h.mu.Unlock()
+}
+
+func (h *Heartbeat) Check() Check {
+	return Check{
+		Name: h.name,
+		Check: func(ctx context.Context) error {
+			h.mu.Lock()
+			last := h.last
+			h.mu.Unlock()
+			if age := time.Since(last); age > h.maxAge {
+				return fmt.Errorf("last ran %s ago", age.Round(time.Second))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,115 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckerReport(t *testing.T) {
+	testcases := []struct {
+		name           string
+		checks         []Check
+		expectedstatus string
+	}{
+		{
+			name:           "no checks",
+			expectedstatus: StatusOK,
+		},
+		{
+			name: "all passing",
+			checks: []Check{
+				{Name: "a", Check: func(ctx context.Context) error { return nil }},
+				{Name: "b", Check: func(ctx context.Context) error { return nil }},
+			},
+			expectedstatus: StatusOK,
+		},
+		{
+			name: "one failing",
+			checks: []Check{
+				{Name: "a", Check: func(ctx context.Context) error { return nil }},
+				{Name: "b", Check: func(ctx context.Context) error { return errors.New("down") }},
+			},
+			expectedstatus: StatusFail,
+		},
+		{
+			name: "timeout",
+			checks: []Check{
+				{Name: "slow", Check: func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				}},
+			},
+			expectedstatus: StatusFail,
+		},
+		{
+			name: "schema behind",
+			checks: []Check{
+				SchemaVersion(func(ctx context.Context) (int64, error) { return 3, nil }, 4),
+			},
+			expectedstatus: StatusFail,
+		},
+		{
+			name: "schema ahead",
+			checks: []Check{
+				SchemaVersion(func(ctx context.Context) (int64, error) { return 5, nil }, 4),
+			},
+			expectedstatus: StatusOK,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := NewChecker(10*time.Millisecond, time.Minute, tc.checks...).Report(context.Background())
+			if report.Status != tc.expectedstatus {
+				t.Errorf("expected status %s, but got %s: %+v", tc.expectedstatus, report.Status, report.Checks)
+			}
+			if len(report.Checks) != len(tc.checks) {
+				t.Errorf("expected %d check results, but got %d", len(tc.checks), len(report.Checks))
+			}
+		})
+	}
+}
+
+func TestCheckerCachesReport(t *testing.T) {
+	calls := 0
+	checker := NewChecker(time.Second, time.Minute, Check{Name: "counted", Check: func(ctx context.Context) error {
+		calls++
+		return nil
+	}})
+	checker.Report(context.Background())
+	checker.Report(context.Background())
+	if calls != 1 {
+		t.Errorf("expected check to run once, but it ran %d times", calls)
+	}
+}
+
+func TestCheckerIgnoresCancelledProbe(t *testing.T) {
+	checker := NewChecker(time.Second, time.Minute, Check{Name: "database", Check: func(ctx context.Context) error {
+		return ctx.Err()
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report := checker.Report(ctx); report.Status != StatusOK {
+		t.Errorf("expected status %s, but got %s: %+v", StatusOK, report.Status, report.Checks)
+	}
+	if report := checker.Report(context.Background()); report.Status != StatusOK {
+		t.Errorf("expected cached status %s, but got %s: %+v", StatusOK, report.Status, report.Checks)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	heartbeat := NewHeartbeat("worker", time.Minute)
+	if err := heartbeat.Check().Check(context.Background()); err != nil {
+		t.Errorf("unexpected error for fresh heartbeat: %v", err)
+	}
+	heartbeat.last = time.Now().Add(-2 * time.Minute)
+	if err := heartbeat.Check().Check(context.Background()); err == nil {
+		t.Errorf("expected an error for stale heartbeat, but got none")
+	}
+	heartbeat.Beat()
+	if err := heartbeat.Check().Check(context.Background()); err != nil {
+		t.Errorf("unexpected error after beat: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package memdb
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+func (db *DB) CreateAuditEvent(ctx context.Context, arg database.CreateAuditEventParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.auditEvents[arg.ID]; ok {
+		return uniqueViolation("audit_events_pkey")
+	}
+	db.auditEvents[arg.ID] = database.AuditEvent{
+		ID:        arg.ID,
+		CreatedAt: timestamp(arg.CreatedAt),
+		Action:    arg.Action,
+		ActorID:   arg.ActorID,
+		TargetID:  arg.TargetID,
+		Ip:        arg.Ip,
+		UserAgent: arg.UserAgent,
+		Metadata:  slices.Clone(arg.Metadata),
+	}
+	return nil
+}
+
+// GetAuditEventsByTarget returns the newest events first. A NULL target
+// matches nothing, like the SQL comparison.
+func (db *DB) GetAuditEventsByTarget(ctx context.Context, arg database.GetAuditEventsByTargetParams) ([]database.AuditEvent, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var events []database.AuditEvent
+	for _, event := range db.auditEvents {
+		if arg.TargetID.Valid && event.TargetID == arg.TargetID {
+			events = append(events, event)
+		}
+	}
+	sortAuditEvents(events)
+	return page(events, arg.Limit, 0), nil
+}
+
+func (db *DB) GetSecurityLog(ctx context.Context, arg database.GetSecurityLogParams) ([]database.AuditEvent, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var events []database.AuditEvent
+	for _, event := range db.auditEvents {
+		if arg.UserID.Valid && (event.ActorID == arg.UserID || event.TargetID == arg.UserID) {
+			events = append(events, event)
+		}
+	}
+	sortAuditEvents(events)
+	return page(events, arg.Limit, arg.Offset), nil
+}
+
+// ListAuditEvents skips each filter that's NULL.
+func (db *DB) ListAuditEvents(ctx context.Context, arg database.ListAuditEventsParams) ([]database.AuditEvent, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var events []database.AuditEvent
+	for _, event := range db.auditEvents {
+		if arg.Action.Valid && event.Action != arg.Action.String {
+			continue
+		}
+		if arg.ActorID.Valid && event.ActorID != arg.ActorID {
+			continue
+		}
+		if arg.TargetID.Valid && event.TargetID != arg.TargetID {
+			continue
+		}
+		if arg.Since.Valid && event.CreatedAt.Before(timestamp(arg.Since.Time)) {
+			continue
+		}
+		if arg.Until.Valid && !event.CreatedAt.Before(timestamp(arg.Until.Time)) {
+			continue
+		}
+		events = append(events, event)
+	}
+	sortAuditEvents(events)
+	return page(events, arg.Limit, arg.Offset), nil
+}
+
+func (db *DB) PseudonymizeAuditEvent(ctx context.Context, arg database.PseudonymizeAuditEventParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	event, ok := db.auditEvents[arg.ID]
+	if !ok {
+		return nil
+	}
+	event.Ip = ""
+	event.UserAgent = ""
+	event.Metadata = slices.Clone(arg.Metadata)
+	db.auditEvents[arg.ID] = event
+	return nil
+}
+
+// sortAuditEvents puts the newest first, breaking ties by ID like the SQL.
+func sortAuditEvents(events []database.AuditEvent) {
+	slices.SortFunc(events, func(a, b database.AuditEvent) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+}
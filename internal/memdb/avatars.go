@@ -0,0 +1,46 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+func (db *DB) DeleteAvatar(ctx context.Context, userID uuid.UUID) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.avatars[userID]; !ok {
+		return 0, nil
+	}
+	delete(db.avatars, userID)
+	return 1, nil
+}
+
+func (db *DB) GetAvatar(ctx context.Context, userID uuid.UUID) (database.Avatar, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	avatar, ok := db.avatars[userID]
+	if !ok {
+		return database.Avatar{}, sql.ErrNoRows
+	}
+	avatar.Data = slices.Clone(avatar.Data)
+	return avatar, nil
+}
+
+func (db *DB) UpsertAvatar(ctx context.Context, arg database.UpsertAvatarParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.users[arg.UserID]; !ok {
+		return foreignKeyViolation("fk_avatars_users")
+	}
+	db.avatars[arg.UserID] = database.Avatar{
+		UserID:      arg.UserID,
+		ContentType: arg.ContentType,
+		Data:        slices.Clone(arg.Data),
+		UpdatedAt:   timestamp(arg.UpdatedAt),
+	}
+	return nil
+}
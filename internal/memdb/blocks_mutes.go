@@ -0,0 +1,96 @@
+package memdb
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+func (db *DB) CreateBlock(ctx context.Context, arg database.CreateBlockParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.users[arg.BlockerID]; !ok {
+		return foreignKeyViolation("fk_blocks_blockers")
+	}
+	if _, ok := db.users[arg.BlockedID]; !ok {
+		return foreignKeyViolation("fk_blocks_blocked")
+	}
+	key := pair{arg.BlockerID, arg.BlockedID}
+	if _, ok := db.blocks[key]; ok {
+		return nil
+	}
+	db.blocks[key] = database.Block{
+		BlockerID: arg.BlockerID,
+		BlockedID: arg.BlockedID,
+		CreatedAt: timestamp(arg.CreatedAt),
+	}
+	return nil
+}
+
+func (db *DB) CreateMute(ctx context.Context, arg database.CreateMuteParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.users[arg.MuterID]; !ok {
+		return foreignKeyViolation("fk_mutes_muters")
+	}
+	if _, ok := db.users[arg.MutedID]; !ok {
+		return foreignKeyViolation("fk_mutes_muted")
+	}
+	key := pair{arg.MuterID, arg.MutedID}
+	if _, ok := db.mutes[key]; ok {
+		return nil
+	}
+	db.mutes[key] = database.Mute{
+		MuterID:   arg.MuterID,
+		MutedID:   arg.MutedID,
+		CreatedAt: timestamp(arg.CreatedAt),
+	}
+	return nil
+}
+
+func (db *DB) DeleteBlock(ctx context.Context, arg database.DeleteBlockParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	delete(db.blocks, pair{arg.BlockerID, arg.BlockedID})
+	return nil
+}
+
+func (db *DB) DeleteMute(ctx context.Context, arg database.DeleteMuteParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	delete(db.mutes, pair{arg.MuterID, arg.MutedID})
+	return nil
+}
+
+func (db *DB) GetBlocksByBlocker(ctx context.Context, blockerID uuid.UUID) ([]database.Block, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var blocks []database.Block
+	for key, block := range db.blocks {
+		if key.from == blockerID {
+			blocks = append(blocks, block)
+		}
+	}
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].CreatedAt.Before(blocks[j].CreatedAt)
+	})
+	return blocks, nil
+}
+
+func (db *DB) GetMutesByMuter(ctx context.Context, muterID uuid.UUID) ([]database.Mute, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var mutes []database.Mute
+	for key, mute := range db.mutes {
+		if key.from == muterID {
+			mutes = append(mutes, mute)
+		}
+	}
+	sort.SliceStable(mutes, func(i, j int) bool {
+		return mutes[i].CreatedAt.Before(mutes[j].CreatedAt)
+	})
+	return mutes, nil
+}
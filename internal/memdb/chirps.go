@@ -0,0 +1,177 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+func (db *DB) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.chirps[arg.ID]; ok {
+		return database.Chirp{}, uniqueViolation("chirps_pkey")
+	}
+	if _, ok := db.users[arg.UserID]; !ok {
+		return database.Chirp{}, foreignKeyViolation("fk_feeds_users")
+	}
+	chirp := database.Chirp{
+		ID:        arg.ID,
+		CreatedAt: timestamp(arg.CreatedAt),
+		UpdatedAt: timestamp(arg.UpdatedAt),
+		Body:      arg.Body,
+		UserID:    arg.UserID,
+	}
+	db.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
+
+func (db *DB) DeleteChirp(ctx context.Context, arg database.DeleteChirpParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	chirp, ok := db.chirps[arg.ID]
+	if !ok || chirp.DeletedAt.Valid {
+		return nil
+	}
+	chirp.DeletedAt = nullTimestamp(arg.DeletedAt)
+	chirp.UpdatedAt = timestamp(arg.UpdatedAt)
+	db.chirps[chirp.ID] = chirp
+	return nil
+}
+
+func (db *DB) GetAllChirps(ctx context.Context, arg database.GetAllChirpsParams) ([]database.Chirp, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var chirps []database.Chirp
+	for _, chirp := range db.chirps {
+		if arg.UserID.Valid && chirp.UserID != arg.UserID.UUID {
+			continue
+		}
+		if chirp.HiddenAt.Valid || chirp.DeletedAt.Valid || db.users[chirp.UserID].DeletedAt.Valid {
+			continue
+		}
+		if arg.ViewerID.Valid {
+			viewer := arg.ViewerID.UUID
+			if _, muted := db.mutes[pair{viewer, chirp.UserID}]; muted {
+				continue
+			}
+			if _, blocked := db.blocks[pair{viewer, chirp.UserID}]; blocked {
+				continue
+			}
+			if _, blocking := db.blocks[pair{chirp.UserID, viewer}]; blocking {
+				continue
+			}
+		}
+		chirps = append(chirps, chirp)
+	}
+	sort.SliceStable(chirps, func(i, j int) bool {
+		return chirps[i].CreatedAt.Before(chirps[j].CreatedAt)
+	})
+	return chirps, nil
+}
+
+func (db *DB) GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	chirp, ok := db.chirps[id]
+	if !ok || chirp.DeletedAt.Valid || db.users[chirp.UserID].DeletedAt.Valid {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+// GetChirpCountsByDay truncates to UTC days, like date_trunc on a TIMESTAMP.
+func (db *DB) GetChirpCountsByDay(ctx context.Context, since time.Time) ([]database.GetChirpCountsByDayRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	counts := map[time.Time]int64{}
+	for _, chirp := range db.chirps {
+		if !chirp.CreatedAt.Before(timestamp(since)) {
+			counts[chirp.CreatedAt.Truncate(24*time.Hour)]++
+		}
+	}
+	var rows []database.GetChirpCountsByDayRow
+	for day, count := range counts {
+		rows = append(rows, database.GetChirpCountsByDayRow{Day: day, Count: count})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Day.Before(rows[j].Day)
+	})
+	return rows, nil
+}
+
+func (db *DB) GetChirpIncludingDeleted(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	chirp, ok := db.chirps[id]
+	if !ok {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+func (db *DB) GetUserChirps(ctx context.Context, userID uuid.UUID) ([]database.Chirp, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var chirps []database.Chirp
+	for _, chirp := range db.chirps {
+		if chirp.UserID == userID {
+			chirps = append(chirps, chirp)
+		}
+	}
+	sort.SliceStable(chirps, func(i, j int) bool {
+		return chirps[i].CreatedAt.Before(chirps[j].CreatedAt)
+	})
+	return chirps, nil
+}
+
+// PurgeDeletedChirps matches nothing when deletedAt is NULL, like the SQL comparison.
+func (db *DB) PurgeDeletedChirps(ctx context.Context, deletedAt sql.NullTime) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if !deletedAt.Valid {
+		return 0, nil
+	}
+	before := timestamp(deletedAt.Time)
+	var purged int64
+	for id, chirp := range db.chirps {
+		if chirp.DeletedAt.Valid && chirp.DeletedAt.Time.Before(before) {
+			db.deleteChirp(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (db *DB) RestoreChirp(ctx context.Context, arg database.RestoreChirpParams) (database.Chirp, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	chirp, ok := db.chirps[arg.ID]
+	if !ok || chirp.UserID != arg.UserID || !chirp.DeletedAt.Valid || !arg.DeletedAt.Valid {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	if !chirp.DeletedAt.Time.After(timestamp(arg.DeletedAt.Time)) {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	chirp.DeletedAt = sql.NullTime{}
+	chirp.UpdatedAt = timestamp(arg.UpdatedAt)
+	db.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
+
+func (db *DB) SetChirpHidden(ctx context.Context, arg database.SetChirpHiddenParams) (database.Chirp, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	chirp, ok := db.chirps[arg.ID]
+	if !ok {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	chirp.HiddenAt = nullTimestamp(arg.HiddenAt)
+	chirp.UpdatedAt = timestamp(arg.UpdatedAt)
+	db.chirps[chirp.ID] = chirp
+	return chirp, nil
+}
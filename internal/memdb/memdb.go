@@ -0,0 +1,193 @@
+// Package memdb is used for running chirpy against an in-memory database in tests
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+// DB implements database.Querier with the same constraints and cascades as
+// the Postgres schema. Constraint violations wrap database.ErrUniqueViolation
+// and database.ErrForeignKeyViolation, and missing rows return sql.ErrNoRows.
+type DB struct {
+	txMu sync.Mutex
+	mu   sync.Mutex
+	tables
+}
+
+type tables struct {
+	users            map[uuid.UUID]database.User
+	chirps           map[uuid.UUID]database.Chirp
+	refreshTokens    map[string]database.RefreshToken
+	reports          map[uuid.UUID]database.Report
+	blocks           map[pair]database.Block
+	mutes            map[pair]database.Mute
+	profaneWords     map[string]database.ProfaneWord
+	rateLimitBuckets map[string]database.RateLimitBucket
+	avatars          map[uuid.UUID]database.Avatar
+	auditEvents      map[uuid.UUID]database.AuditEvent
+}
+
+var (
+	_ database.Querier    = (*DB)(nil)
+	_ database.Transactor = (*DB)(nil)
+)
+
+type pair struct {
+	from uuid.UUID
+	to   uuid.UUID
+}
+
+func New() *DB {
+	return &DB{tables: tables{
+		users:            map[uuid.UUID]database.User{},
+		chirps:           map[uuid.UUID]database.Chirp{},
+		refreshTokens:    map[string]database.RefreshToken{},
+		reports:          map[uuid.UUID]database.Report{},
+		blocks:           map[pair]database.Block{},
+		mutes:            map[pair]database.Mute{},
+		profaneWords:     map[string]database.ProfaneWord{},
+		rateLimitBuckets: map[string]database.RateLimitBucket{},
+		avatars:          map[uuid.UUID]database.Avatar{},
+		auditEvents:      map[uuid.UUID]database.AuditEvent{},
+	}}
+}
+
+// AddProfaneWord stands in for rows an operator inserts by hand.
+func (db *DB) AddProfaneWord(word, action string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.profaneWords[word] = database.ProfaneWord{Word: word, Action: action, CreatedAt: timestamp(time.Now())}
+}
+
+// MakeAdmin stands in for an operator setting is_admin by hand.
+func (db *DB) MakeAdmin(userID uuid.UUID) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if user, ok := db.users[userID]; ok {
+		user.IsAdmin = true
+		db.users[userID] = user
+	}
+}
+
+// timestamp stores t the way a Postgres TIMESTAMP column does: the wall
+// clock without a time zone, to the microsecond.
+func timestamp(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC).Round(time.Microsecond)
+}
+
+func nullTimestamp(t sql.NullTime) sql.NullTime {
+	if !t.Valid {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: timestamp(t.Time), Valid: true}
+}
+
+func uniqueViolation(constraint string) error {
+	return fmt.Errorf("%w: %s", database.ErrUniqueViolation, constraint)
+}
+
+func foreignKeyViolation(constraint string) error {
+	return fmt.Errorf("%w: %s", database.ErrForeignKeyViolation, constraint)
+}
+
+// page applies LIMIT and OFFSET to rows that are already sorted.
+func page[T any](rows []T, limit, offset int32) []T {
+	if int(offset) >= len(rows) {
+		return nil
+	}
+	rows = rows[offset:]
+	if int(limit) < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// deleteChirp removes a chirp and the reports on it. db.mu must be held.
+func (db *DB) deleteChirp(id uuid.UUID) {
+	delete(db.chirps, id)
+	for reportID, report := range db.reports {
+		if report.ChirpID == id {
+			delete(db.reports, reportID)
+		}
+	}
+}
+
+// deleteUser removes a user and everything that references them, including
+// reports on their chirps. db.mu must be held.
+func (db *DB) deleteUser(id uuid.UUID) {
+	delete(db.users, id)
+	delete(db.avatars, id)
+	for chirpID, chirp := range db.chirps {
+		if chirp.UserID == id {
+			db.deleteChirp(chirpID)
+		}
+	}
+	for token, refreshToken := range db.refreshTokens {
+		if refreshToken.UserID == id {
+			delete(db.refreshTokens, token)
+		}
+	}
+	for reportID, report := range db.reports {
+		if report.ReporterID == id {
+			delete(db.reports, reportID)
+		} else if report.ResolvedBy.Valid && report.ResolvedBy.UUID == id {
+			report.ResolvedBy = uuid.NullUUID{}
+			db.reports[reportID] = report
+		}
+	}
+	for key := range db.blocks {
+		if key.from == id || key.to == id {
+			delete(db.blocks, key)
+		}
+	}
+	for key := range db.mutes {
+		if key.from == id || key.to == id {
+			delete(db.mutes, key)
+		}
+	}
+}
+
+// InTx gives transactions all-or-nothing semantics by restoring a snapshot
+// when fn fails. Transactions run one at a time but aren't isolated from
+// queries made outside them.
+func (db *DB) InTx(ctx context.Context, fn func(q database.Querier) error) error {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+	snapshot := db.snapshot()
+	if err := fn(db); err != nil {
+		db.restore(snapshot)
+		return err
+	}
+	return nil
+}
+
+func (db *DB) snapshot() tables {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return tables{
+		users:            maps.Clone(db.users),
+		chirps:           maps.Clone(db.chirps),
+		refreshTokens:    maps.Clone(db.refreshTokens),
+		reports:          maps.Clone(db.reports),
+		blocks:           maps.Clone(db.blocks),
+		mutes:            maps.Clone(db.mutes),
+		profaneWords:     maps.Clone(db.profaneWords),
+		rateLimitBuckets: maps.Clone(db.rateLimitBuckets),
+		avatars:          maps.Clone(db.avatars),
+		auditEvents:      maps.Clone(db.auditEvents),
+	}
+}
+
+func (db *DB) restore(t tables) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.tables = t
+}
@@ -0,0 +1,21 @@
+package memdb
+
+import (
+	"testing"
+
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/database/databasetest"
+)
+
+func TestConformance(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T) database.Querier {
+		return New()
+	})
+}
+
+func TestTransactor(t *testing.T) {
+	databasetest.RunTransactor(t, func(t *testing.T) (database.Querier, database.Transactor) {
+		db := New()
+		return db, db
+	})
+}
@@ -0,0 +1,21 @@
+package memdb
+
+import (
+	"context"
+	"sort"
+
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+func (db *DB) GetProfaneWords(ctx context.Context) ([]database.GetProfaneWordsRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var words []database.GetProfaneWordsRow
+	for _, word := range db.profaneWords {
+		words = append(words, database.GetProfaneWordsRow{Word: word.Word, Action: word.Action})
+	}
+	sort.Slice(words, func(i, j int) bool {
+		return words[i].Word < words[j].Word
+	})
+	return words, nil
+}
@@ -0,0 +1,42 @@
+package memdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+func (db *DB) DeleteStaleRateLimitBuckets(ctx context.Context, updatedAt time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	before := timestamp(updatedAt)
+	for key, bucket := range db.rateLimitBuckets {
+		if bucket.UpdatedAt.Before(before) {
+			delete(db.rateLimitBuckets, key)
+		}
+	}
+	return nil
+}
+
+// TakeRateLimitToken refills the bucket for the time since its last update
+// and takes a token when a whole one is available, like the upsert in SQL.
+func (db *DB) TakeRateLimitToken(ctx context.Context, arg database.TakeRateLimitTokenParams) (database.TakeRateLimitTokenRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	now := timestamp(arg.Now)
+	bucket, ok := db.rateLimitBuckets[arg.Key]
+	if !ok {
+		bucket = database.RateLimitBucket{Key: arg.Key, Tokens: arg.Burst - 1, Allowed: true, UpdatedAt: now}
+	} else {
+		tokens := min(arg.Burst, bucket.Tokens+max(0, now.Sub(bucket.UpdatedAt).Seconds())*arg.Rate)
+		bucket.Allowed = tokens >= 1
+		if bucket.Allowed {
+			tokens--
+		}
+		bucket.Tokens = tokens
+		bucket.UpdatedAt = now
+	}
+	db.rateLimitBuckets[arg.Key] = bucket
+	return database.TakeRateLimitTokenRow{Tokens: bucket.Tokens, Allowed: bucket.Allowed}, nil
+}
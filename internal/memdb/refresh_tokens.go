@@ -0,0 +1,94 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+func (db *DB) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.refreshTokens[arg.Token]; ok {
+		return database.RefreshToken{}, uniqueViolation("refresh_tokens_pkey")
+	}
+	if _, ok := db.users[arg.UserID]; !ok {
+		return database.RefreshToken{}, foreignKeyViolation("fk_feeds_users")
+	}
+	token := database.RefreshToken{
+		Token:     arg.Token,
+		CreatedAt: timestamp(arg.CreatedAt),
+		UpdatedAt: timestamp(arg.UpdatedAt),
+		ExpiresAt: timestamp(arg.ExpiresAt),
+		UserID:    arg.UserID,
+	}
+	db.refreshTokens[token.Token] = token
+	return token, nil
+}
+
+func (db *DB) GetTokenInfo(ctx context.Context, token string) (database.GetTokenInfoRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	refreshToken, ok := db.refreshTokens[token]
+	if !ok {
+		return database.GetTokenInfoRow{}, sql.ErrNoRows
+	}
+	user := db.users[refreshToken.UserID]
+	return database.GetTokenInfoRow{
+		Token:       refreshToken.Token,
+		CreatedAt:   refreshToken.CreatedAt,
+		UpdatedAt:   refreshToken.UpdatedAt,
+		ExpiresAt:   refreshToken.ExpiresAt,
+		RevokedAt:   refreshToken.RevokedAt,
+		UserID:      refreshToken.UserID,
+		ID:          user.ID,
+		Email:       user.Email,
+		SuspendedAt: user.SuspendedAt,
+	}, nil
+}
+
+func (db *DB) GetUserRefreshTokens(ctx context.Context, userID uuid.UUID) ([]database.RefreshToken, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var tokens []database.RefreshToken
+	for _, token := range db.refreshTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+func (db *DB) RevokeToken(ctx context.Context, arg database.RevokeTokenParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	token, ok := db.refreshTokens[arg.Token]
+	if !ok {
+		return nil
+	}
+	token.RevokedAt = nullTimestamp(arg.RevokedAt)
+	token.UpdatedAt = timestamp(arg.UpdatedAt)
+	db.refreshTokens[token.Token] = token
+	return nil
+}
+
+func (db *DB) RevokeUserTokens(ctx context.Context, arg database.RevokeUserTokensParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, token := range db.refreshTokens {
+		if token.UserID != arg.UserID || token.RevokedAt.Valid {
+			continue
+		}
+		token.RevokedAt = nullTimestamp(arg.RevokedAt)
+		token.UpdatedAt = timestamp(arg.UpdatedAt)
+		db.refreshTokens[token.Token] = token
+	}
+	return nil
+}
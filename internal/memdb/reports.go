@@ -0,0 +1,160 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+var (
+	reportReasons  = map[string]bool{"spam": true, "harassment": true, "hate": true, "violence": true, "misinformation": true, "other": true}
+	reportStatuses = map[string]bool{"open": true, "dismissed": true, "actioned": true}
+)
+
+func (db *DB) CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if !reportReasons[arg.Reason] {
+		return database.Report{}, fmt.Errorf("reason %q violates check constraint chk_reports_reason", arg.Reason)
+	}
+	if _, ok := db.reports[arg.ID]; ok {
+		return database.Report{}, uniqueViolation("reports_pkey")
+	}
+	for _, report := range db.reports {
+		if report.ChirpID == arg.ChirpID && report.ReporterID == arg.ReporterID {
+			return database.Report{}, uniqueViolation("uq_reports_chirp_reporter")
+		}
+	}
+	if _, ok := db.chirps[arg.ChirpID]; !ok {
+		return database.Report{}, foreignKeyViolation("fk_reports_chirps")
+	}
+	if _, ok := db.users[arg.ReporterID]; !ok {
+		return database.Report{}, foreignKeyViolation("fk_reports_users")
+	}
+	report := database.Report{
+		ID:         arg.ID,
+		CreatedAt:  timestamp(arg.CreatedAt),
+		UpdatedAt:  timestamp(arg.UpdatedAt),
+		ChirpID:    arg.ChirpID,
+		ReporterID: arg.ReporterID,
+		Reason:     arg.Reason,
+		Details:    arg.Details,
+		Status:     "open",
+	}
+	db.reports[report.ID] = report
+	return report, nil
+}
+
+func (db *DB) GetOpenReports(ctx context.Context) ([]database.Report, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var reports []database.Report
+	for _, report := range db.reports {
+		if report.Status == "open" {
+			reports = append(reports, report)
+		}
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].CreatedAt.Before(reports[j].CreatedAt)
+	})
+	return reports, nil
+}
+
+// GetOpenReportsWithChirps leaves out reports whose chirp is missing, like
+// the SQL join, though the foreign key means there never are any.
+func (db *DB) GetOpenReportsWithChirps(ctx context.Context) ([]database.GetOpenReportsWithChirpsRow, error) {
+	reports, err := db.GetOpenReports(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var rows []database.GetOpenReportsWithChirpsRow
+	for _, report := range reports {
+		chirp, ok := db.chirps[report.ChirpID]
+		if !ok {
+			continue
+		}
+		rows = append(rows, database.GetOpenReportsWithChirpsRow{Report: report, ChirpBody: chirp.Body})
+	}
+	return rows, nil
+}
+
+func (db *DB) GetReport(ctx context.Context, id uuid.UUID) (database.Report, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	report, ok := db.reports[id]
+	if !ok {
+		return database.Report{}, sql.ErrNoRows
+	}
+	return report, nil
+}
+
+func (db *DB) GetReportsByReporter(ctx context.Context, reporterID uuid.UUID) ([]database.Report, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var reports []database.Report
+	for _, report := range db.reports {
+		if report.ReporterID == reporterID {
+			reports = append(reports, report)
+		}
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].CreatedAt.Before(reports[j].CreatedAt)
+	})
+	return reports, nil
+}
+
+func (db *DB) ResolveChirpReports(ctx context.Context, arg database.ResolveChirpReportsParams) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err := db.checkResolution(arg.Status, arg.ResolvedBy); err != nil {
+		return err
+	}
+	for _, report := range db.reports {
+		if report.ChirpID != arg.ChirpID || report.Status != "open" {
+			continue
+		}
+		db.reports[report.ID] = resolve(report, arg.Status, arg.ResolvedAt, arg.ResolvedBy, arg.UpdatedAt)
+	}
+	return nil
+}
+
+func (db *DB) ResolveReport(ctx context.Context, arg database.ResolveReportParams) (database.Report, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	report, ok := db.reports[arg.ID]
+	if !ok || report.Status != "open" {
+		return database.Report{}, sql.ErrNoRows
+	}
+	if err := db.checkResolution(arg.Status, arg.ResolvedBy); err != nil {
+		return database.Report{}, err
+	}
+	report = resolve(report, arg.Status, arg.ResolvedAt, arg.ResolvedBy, arg.UpdatedAt)
+	db.reports[report.ID] = report
+	return report, nil
+}
+
+// checkResolution applies the status check and resolver foreign key. db.mu must be held.
+func (db *DB) checkResolution(status string, resolvedBy uuid.NullUUID) error {
+	if !reportStatuses[status] {
+		return fmt.Errorf("status %q violates check constraint chk_reports_status", status)
+	}
+	if _, ok := db.users[resolvedBy.UUID]; resolvedBy.Valid && !ok {
+		return foreignKeyViolation("fk_reports_resolvers")
+	}
+	return nil
+}
+
+func resolve(report database.Report, status string, resolvedAt sql.NullTime, resolvedBy uuid.NullUUID, updatedAt time.Time) database.Report {
+	report.Status = status
+	report.ResolvedAt = nullTimestamp(resolvedAt)
+	report.ResolvedBy = resolvedBy
+	report.UpdatedAt = timestamp(updatedAt)
+	return report
+}
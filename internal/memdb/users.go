@@ -0,0 +1,329 @@
+package memdb
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+func (db *DB) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.users[arg.ID]; ok {
+		return database.User{}, uniqueViolation("users_pkey")
+	}
+	if db.emailTaken(arg.Email, uuid.Nil) {
+		return database.User{}, uniqueViolation("users_email_key")
+	}
+	user := database.User{
+		ID:             arg.ID,
+		CreatedAt:      timestamp(arg.CreatedAt),
+		UpdatedAt:      timestamp(arg.UpdatedAt),
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+	}
+	db.users[user.ID] = user
+	return user, nil
+}
+
+// DeleteUsers cascades to everything that references a user, which is every
+// table except profane_words and rate_limit_buckets.
+func (db *DB) DeleteUsers(ctx context.Context) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	clear(db.users)
+	clear(db.chirps)
+	clear(db.refreshTokens)
+	clear(db.reports)
+	clear(db.blocks)
+	clear(db.mutes)
+	clear(db.avatars)
+	return nil
+}
+
+func (db *DB) GetUser(ctx context.Context, email string) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, user := range db.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (db *DB) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, ok := db.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+// GetAuthors returns the users in ids ordered by ID, skipping ones that don't
+// exist.
+func (db *DB) GetAuthors(ctx context.Context, ids []uuid.UUID) ([]database.GetAuthorsRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var authors []database.GetAuthorsRow
+	for _, id := range ids {
+		user, ok := db.users[id]
+		if !ok || slices.ContainsFunc(authors, func(author database.GetAuthorsRow) bool { return author.ID == id }) {
+			continue
+		}
+		_, hasAvatar := db.avatars[id]
+		authors = append(authors, database.GetAuthorsRow{
+			ID:          user.ID,
+			Handle:      user.Handle,
+			DisplayName: user.DisplayName,
+			HasAvatar:   hasAvatar,
+		})
+	}
+	slices.SortFunc(authors, func(a, b database.GetAuthorsRow) int {
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+	return authors, nil
+}
+
+// GetRecentUsers returns the newest users first.
+func (db *DB) GetRecentUsers(ctx context.Context, limit int32) ([]database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var users []database.User
+	for _, user := range db.users {
+		users = append(users, user)
+	}
+	slices.SortFunc(users, func(a, b database.User) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+	return page(users, limit, 0), nil
+}
+
+func (db *DB) GetUserProfile(ctx context.Context, handle sql.NullString) (database.GetUserProfileRow, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, user := range db.users {
+		if !handle.Valid || user.Handle != handle || user.DeletedAt.Valid {
+			continue
+		}
+		var chirpCount int64
+		for _, chirp := range db.chirps {
+			if chirp.UserID == user.ID && !chirp.HiddenAt.Valid && !chirp.DeletedAt.Valid {
+				chirpCount++
+			}
+		}
+		_, hasAvatar := db.avatars[user.ID]
+		return database.GetUserProfileRow{
+			ID:             user.ID,
+			CreatedAt:      user.CreatedAt,
+			UpdatedAt:      user.UpdatedAt,
+			Email:          user.Email,
+			HashedPassword: user.HashedPassword,
+			IsChirpyRed:    user.IsChirpyRed,
+			IsAdmin:        user.IsAdmin,
+			SuspendedAt:    user.SuspendedAt,
+			Handle:         user.Handle,
+			DisplayName:    user.DisplayName,
+			Bio:            user.Bio,
+			DeletedAt:      user.DeletedAt,
+			ChirpCount:     chirpCount,
+			HasAvatar:      hasAvatar,
+		}, nil
+	}
+	return database.GetUserProfileRow{}, sql.ErrNoRows
+}
+
+// GetPurgeableUserIDs matches nothing when deletedAt is NULL, like the SQL
+// comparison.
+func (db *DB) GetPurgeableUserIDs(ctx context.Context, deletedAt sql.NullTime) ([]uuid.UUID, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if !deletedAt.Valid {
+		return nil, nil
+	}
+	before := timestamp(deletedAt.Time)
+	var ids []uuid.UUID
+	for id, user := range db.users {
+		if user.DeletedAt.Valid && user.DeletedAt.Time.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// PurgeDeletedUsers matches nothing when deletedAt is NULL, like the SQL comparison.
+func (db *DB) PurgeDeletedUsers(ctx context.Context, deletedAt sql.NullTime) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if !deletedAt.Valid {
+		return 0, nil
+	}
+	before := timestamp(deletedAt.Time)
+	var purged int64
+	for id, user := range db.users {
+		if user.DeletedAt.Valid && user.DeletedAt.Time.Before(before) {
+			db.deleteUser(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// SearchUsers matches query anywhere in the email, ignoring case. Like the
+// SQL, a backslash escapes the next character, and % and _ are wildcards.
+func (db *DB) SearchUsers(ctx context.Context, arg database.SearchUsersParams) ([]database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	pattern := likePattern(strings.ToLower(arg.Query))
+	var users []database.User
+	for _, user := range db.users {
+		if pattern.MatchString(strings.ToLower(user.Email)) {
+			users = append(users, user)
+		}
+	}
+	slices.SortFunc(users, func(a, b database.User) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+	return page(users, arg.Limit, arg.Offset), nil
+}
+
+func (db *DB) SetPasswordResetRequired(ctx context.Context, arg database.SetPasswordResetRequiredParams) (database.User, error) {
+	return db.updateUser(arg.ID, func(user *database.User) error {
+		user.PasswordResetRequired = arg.PasswordResetRequired
+		user.UpdatedAt = timestamp(arg.UpdatedAt)
+		return nil
+	})
+}
+
+func (db *DB) SetUserAdmin(ctx context.Context, arg database.SetUserAdminParams) (database.User, error) {
+	return db.updateUser(arg.ID, func(user *database.User) error {
+		user.IsAdmin = arg.IsAdmin
+		user.UpdatedAt = timestamp(arg.UpdatedAt)
+		return nil
+	})
+}
+
+func (db *DB) SetUserDeleted(ctx context.Context, arg database.SetUserDeletedParams) (database.User, error) {
+	return db.updateUser(arg.ID, func(user *database.User) error {
+		user.DeletedAt = nullTimestamp(arg.DeletedAt)
+		user.UpdatedAt = timestamp(arg.UpdatedAt)
+		return nil
+	})
+}
+
+func (db *DB) SetUserSuspended(ctx context.Context, arg database.SetUserSuspendedParams) (database.User, error) {
+	return db.updateUser(arg.ID, func(user *database.User) error {
+		user.SuspendedAt = nullTimestamp(arg.SuspendedAt)
+		user.UpdatedAt = timestamp(arg.UpdatedAt)
+		return nil
+	})
+}
+
+func (db *DB) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error) {
+	return db.updateUser(arg.ID, func(user *database.User) error {
+		if db.emailTaken(arg.Email, user.ID) {
+			return uniqueViolation("users_email_key")
+		}
+		user.Email = arg.Email
+		user.PasswordResetRequired = user.PasswordResetRequired && user.HashedPassword == arg.HashedPassword
+		user.HashedPassword = arg.HashedPassword
+		user.UpdatedAt = timestamp(arg.UpdatedAt)
+		return nil
+	})
+}
+
+func (db *DB) UpdateUserProfile(ctx context.Context, arg database.UpdateUserProfileParams) (database.User, error) {
+	return db.updateUser(arg.ID, func(user *database.User) error {
+		if db.handleTaken(arg.Handle, user.ID) {
+			return uniqueViolation("users_handle_key")
+		}
+		user.Handle = arg.Handle
+		user.DisplayName = arg.DisplayName
+		user.Bio = arg.Bio
+		user.UpdatedAt = timestamp(arg.UpdatedAt)
+		return nil
+	})
+}
+
+func (db *DB) UpgradeUser(ctx context.Context, arg database.UpgradeUserParams) (database.User, error) {
+	return db.updateUser(arg.ID, func(user *database.User) error {
+		user.IsChirpyRed = arg.IsChirpyRed
+		user.UpdatedAt = timestamp(arg.UpdatedAt)
+		return nil
+	})
+}
+
+func (db *DB) updateUser(id uuid.UUID, update func(user *database.User) error) (database.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	user, ok := db.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	if err := update(&user); err != nil {
+		return database.User{}, err
+	}
+	db.users[id] = user
+	return user, nil
+}
+
+// likePattern turns the part of a LIKE pattern between the surrounding %s
+// into a regular expression.
+func likePattern(query string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?s)")
+	escaped := false
+	for _, r := range query {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return regexp.MustCompile(b.String())
+}
+
+// emailTaken reports whether a user other than except has email. db.mu must be held.
+func (db *DB) emailTaken(email string, except uuid.UUID) bool {
+	for _, user := range db.users {
+		if user.Email == email && user.ID != except {
+			return true
+		}
+	}
+	return false
+}
+
+// handleTaken reports whether a user other than except has handle. NULL
+// handles never clash. db.mu must be held.
+func (db *DB) handleTaken(handle sql.NullString, except uuid.UUID) bool {
+	if !handle.Valid {
+		return false
+	}
+	for _, user := range db.users {
+		if user.Handle == handle && user.ID != except {
+			return true
+		}
+	}
+	return false
+}
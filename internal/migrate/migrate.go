@@ -0,0 +1,105 @@
+// Package migrate is used for applying the embedded schema migrations
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/marekbrze/chirpy/sql/schema"
+	"github.com/marekbrze/chirpy/sql/schema/sqlite"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// ErrSchemaBehind is returned by Check when migrations are still pending.
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+type Migrator struct {
+	provider *goose.Provider
+}
+
+// New doesn't touch the database. A Postgres advisory lock is taken while
+// migrating, so several instances starting at once apply each migration once.
+func New(db *sql.DB) (*Migrator, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, err
+	}
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, schema.FS, goose.WithSessionLocker(locker))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{provider: provider}, nil
+}
+
+// NewSQLite uses the separate SQLite migrations. SQLite has a single writer,
+// so no lock is needed.
+func NewSQLite(db *sql.DB) (*Migrator, error) {
+	provider, err := goose.NewProvider(goose.DialectSQLite3, db, sqlite.FS)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{provider: provider}, nil
+}
+
+// Latest is the newest embedded migration, which the queries in
+// internal/database are generated against.
+func (m *Migrator) Latest() int64 {
+	sources := m.provider.ListSources()
+	return sources[len(sources)-1].Version
+}
+
+// Current is the newest migration applied to the database.
+func (m *Migrator) Current(ctx context.Context) (int64, error) {
+	current, _, err := m.provider.GetVersions(ctx)
+	return current, err
+}
+
+// Check fails with ErrSchemaBehind when the database hasn't been migrated to
+// Latest. A newer schema is fine, so an older binary can still roll back.
+func (m *Migrator) Check(ctx context.Context) error {
+	current, err := m.Current(ctx)
+	if err != nil {
+		return err
+	}
+	if current < m.Latest() {
+		return fmt.Errorf("%w: version is %d, expected %d", ErrSchemaBehind, current, m.Latest())
+	}
+	return nil
+}
+
+// Up applies every pending migration. When one fails the results include
+// the migrations applied before it and the failed one.
+func (m *Migrator) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	results, err := m.provider.Up(ctx)
+	var partial *goose.PartialError
+	if errors.As(err, &partial) {
+		results = append(partial.Applied, partial.Failed)
+	}
+	return results, err
+}
+
+// Down rolls back the newest applied migration.
+func (m *Migrator) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	return m.provider.Down(ctx)
+}
+
+// Status writes one line per embedded migration saying whether it's applied.
+func (m *Migrator) Status(ctx context.Context, w io.Writer) error {
+	statuses, err := m.provider.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		appliedAt := "-"
+		if !status.AppliedAt.IsZero() {
+			appliedAt = status.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%-8s %-20s %s\n", status.State, appliedAt, path.Base(status.Source.Path))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/marekbrze/chirpy/internal/sqlitedb"
+	"github.com/marekbrze/chirpy/sql/schema"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	db, err := sql.Open("postgres", "postgres://localhost/chirpy_test?sslmode=disable")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files, err := fs.Glob(schema.FS, "*.sql")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sources := migrator.provider.ListSources()
+	if len(sources) != len(files) {
+		t.Fatalf("expected %d migrations, but got %d", len(files), len(sources))
+	}
+	for i, source := range sources {
+		if source.Version != int64(i+1) {
+			t.Errorf("expected migration %s to be version %d, but got %d", source.Path, i+1, source.Version)
+		}
+	}
+	if migrator.Latest() != int64(len(files)) {
+		t.Errorf("expected latest version %d, but got %d", len(files), migrator.Latest())
+	}
+}
+
+func TestSQLiteUpAndDown(t *testing.T) {
+	db, err := sqlitedb.Open("sqlite::memory:")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+	migrator, err := NewSQLite(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := migrator.Check(ctx); !errors.Is(err, ErrSchemaBehind) {
+		t.Errorf("expected ErrSchemaBehind on an empty database, but got %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("unexpected error migrating up: %v", err)
+	}
+	if err := migrator.Check(ctx); err != nil {
+		t.Errorf("expected the schema to be current, but got %v", err)
+	}
+	if _, err := migrator.Down(ctx); err != nil {
+		t.Fatalf("unexpected error migrating down: %v", err)
+	}
+	if err := migrator.Check(ctx); !errors.Is(err, ErrSchemaBehind) {
+		t.Errorf("expected ErrSchemaBehind after migrating down, but got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+// Package problem is used for describing API errors as RFC 7807 problem details
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const ContentType = "application/problem+json"
+
+// Type is a kind of problem. Every problem of one type has the same status
+// and title; what went wrong this time goes in the detail.
+type Type struct {
+	Name   string
+	Status int
+	Title  string
+}
+
+var (
+	MalformedRequest     = Type{Name: "malformed-request", Status: http.StatusBadRequest, Title: "Malformed request"}
+	InvalidInput         = Type{Name: "invalid-input", Status: http.StatusBadRequest, Title: "Invalid input"}
+	Unauthorized         = Type{Name: "unauthorized", Status: http.StatusUnauthorized, Title: "Unauthorized"}
+	Forbidden            = Type{Name: "forbidden", Status: http.StatusForbidden, Title: "Forbidden"}
+	NotFound             = Type{Name: "not-found", Status: http.StatusNotFound, Title: "Not found"}
+	Conflict             = Type{Name: "conflict", Status: http.StatusConflict, Title: "Conflict"}
+	ContentTooLarge      = Type{Name: "content-too-large", Status: http.StatusRequestEntityTooLarge, Title: "Content too large"}
+	UnsupportedMediaType = Type{Name: "unsupported-media-type", Status: http.StatusUnsupportedMediaType, Title: "Unsupported media type"}
+	TooManyRequests      = Type{Name: "too-many-requests", Status: http.StatusTooManyRequests, Title: "Too many requests"}
+	Internal             = Type{Name: "internal", Status: http.StatusInternalServerError, Title: "Something went wrong"}
+)
+
+// URI identifies the type in the type member. It's relative to the API, so
+// the same build works behind any host name.
+func (t Type) URI() string {
+	return "/problems/" + t.Name
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error that knows how it's shown to clients. Err is the
+// cause; it's for logs and is never sent to the client.
+type Error struct {
+	Type   Type
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+func New(t Type, detail string) *Error {
+	return &Error{Type: t, Detail: detail}
+}
+
+// Invalid reports input that parsed but broke the rules for one or more
+// fields.
+func Invalid(fields ...FieldError) *Error {
+	return &Error{Type: InvalidInput, Detail: "The request has invalid fields", Fields: fields}
+}
+
+func Wrap(t Type, err error, detail string) *Error {
+	return &Error{Type: t, Detail: detail, Err: err}
+}
+
+func (e *Error) Error() string {
+	msg := e.Type.Title
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Details is the body of an application/problem+json response.
+type Details struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// Details describes the error as it happened at instance, usually the path
+// of the request.
+func (e *Error) Details(instance string) Details {
+	return Details{
+		Type:     e.Type.URI(),
+		Title:    e.Type.Title,
+		Status:   e.Type.Status,
+		Detail:   e.Detail,
+		Instance: instance,
+		Errors:   e.Fields,
+	}
+}
+
+func Write(w http.ResponseWriter, details Details) error {
+	body, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(details.Status)
+	_, err = w.Write(body)
+	return err
+}
@@ -0,0 +1,56 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := Wrap(Internal, cause, "")
+	if !errors.Is(err, cause) {
+		t.Errorf("expected the problem to wrap %v", cause)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := Write(rec, err.Details("/api/chirps")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != 500 {
+		t.Errorf("expected status 500, but got %d", rec.Code)
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != ContentType {
+		t.Errorf("expected content type %q, but got %q", ContentType, contentType)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("unexpected error decoding: %v", err)
+	}
+	expected := map[string]any{
+		"type":     "/problems/internal",
+		"title":    "Something went wrong",
+		"status":   float64(500),
+		"instance": "/api/chirps",
+	}
+	if len(body) != len(expected) {
+		t.Errorf("expected %v, but got %v", expected, body)
+	}
+	for key, value := range expected {
+		if body[key] != value {
+			t.Errorf("expected %s to be %v, but got %v", key, value, body[key])
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	err := Invalid(FieldError{Field: "email", Message: "is required"}, FieldError{Field: "password", Message: "is required"})
+	details := err.Details("/api/users")
+	if details.Status != 400 || details.Type != "/problems/invalid-input" {
+		t.Errorf("expected an invalid-input problem with status 400, but got %+v", details)
+	}
+	if len(details.Errors) != 2 {
+		t.Errorf("expected 2 field errors, but got %+v", details.Errors)
+	}
+}
@@ -0,0 +1,250 @@
+// Package profanity is used for finding and handling banned words in chirps
+package profanity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+type Action string
+
+const (
+	ActionMask   Action = "mask"
+	ActionReject Action = "reject"
+	ActionFlag   Action = "flag"
+)
+
+const mask = "****"
+
+type Rule struct {
+	Word   string
+	Action Action
+}
+
+type Match struct {
+	Word   string
+	Action Action
+}
+
+type Result struct {
+	Text     string
+	Rejected bool
+	Flagged  bool
+	Matches  []Match
+}
+
+// DefaultRules are the words chirpy has always masked.
+var DefaultRules = []Rule{
+	{Word: "kerfuffle", Action: ActionMask},
+	{Word: "sharbert", Action: ActionMask},
+	{Word: "fornax", Action: ActionMask},
+}
+
+var leetspeak = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'@': 'a',
+	'$': 's',
+}
+
+type Filter struct {
+	mu      sync.RWMutex
+	rules   map[string]Action
+	sources []Source
+
+	// reloading guards loaded, the last rules each source loaded
+	// successfully.
+	reloading sync.Mutex
+	loaded    [][]Rule
+}
+
+func New(sources ...Source) *Filter {
+	return &Filter{
+		rules:   map[string]Action{},
+		sources: sources,
+		loaded:  make([][]Rule, len(sources)),
+	}
+}
+
+// Reload replaces the current word list with the rules from all sources.
+// When the same word comes from more than one source the strictest action wins.
+// A source that fails keeps its previous rules, so the database being down
+// doesn't take the built-in and file rules with it. The errors are returned
+// once everything else has been installed.
+func (f *Filter) Reload(ctx context.Context) error {
+	f.reloading.Lock()
+	defer f.reloading.Unlock()
+	var errs []error
+	for i, source := range f.sources {
+		loaded, err := loadRules(ctx, source)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		f.loaded[i] = loaded
+	}
+	rules := map[string]Action{}
+	for _, loaded := range f.loaded {
+		for _, rule := range loaded {
+			word := Normalize(rule.Word)
+			if word == "" {
+				continue
+			}
+			if current, ok := rules[word]; !ok || rule.Action.stricterThan(current) {
+				rules[word] = rule.Action
+			}
+		}
+	}
+	f.mu.Lock()
+	f.rules = rules
+	f.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+func loadRules(ctx context.Context, source Source) ([]Rule, error) {
+	loaded, err := source.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range loaded {
+		if err := rule.Action.validate(); err != nil {
+			return nil, fmt.Errorf("word %q: %w", rule.Word, err)
+		}
+	}
+	return loaded, nil
+}
+
+func (f *Filter) Check(msg string) Result {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	result := Result{}
+	var cleaned strings.Builder
+	for _, token := range tokenize(msg) {
+		if !token.word {
+			cleaned.WriteString(token.text)
+			continue
+		}
+		word, prefix, suffix := token.text, "", ""
+		action, ok := f.rules[Normalize(word)]
+		if !ok {
+			word, prefix, suffix = trimSymbols(token.text)
+			action, ok = f.rules[Normalize(word)]
+		}
+		if !ok {
+			cleaned.WriteString(token.text)
+			continue
+		}
+		result.Matches = append(result.Matches, Match{Word: word, Action: action})
+		switch action {
+		case ActionMask:
+			cleaned.WriteString(prefix + mask + suffix)
+		case ActionReject:
+			result.Rejected = true
+			cleaned.WriteString(token.text)
+		case ActionFlag:
+			result.Flagged = true
+			cleaned.WriteString(token.text)
+		}
+	}
+	result.Text = cleaned.String()
+	return result
+}
+
+// Normalize folds a word to the form used for matching: compatibility
+// decomposition without accents, lower case and leetspeak replaced with letters.
+func Normalize(word string) string {
+	t := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, word)
+	if err != nil {
+		folded = word
+	}
+	folded = strings.ToLower(folded)
+	return strings.Map(func(r rune) rune {
+		if replacement, ok := leetspeak[r]; ok {
+			return replacement
+		}
+		return r
+	}, folded)
+}
+
+type token struct {
+	text string
+	word bool
+}
+
+func tokenize(msg string) []token {
+	var tokens []token
+	start := 0
+	inWord := false
+	for i, r := range msg {
+		if isWordRune(r) != inWord {
+			if i > start {
+				tokens = append(tokens, token{text: msg[start:i], word: inWord})
+			}
+			start = i
+			inWord = !inWord
+		}
+	}
+	if start < len(msg) {
+		tokens = append(tokens, token{text: msg[start:], word: inWord})
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+		return true
+	}
+	return isSymbol(r)
+}
+
+// isSymbol reports whether r is leetspeak that isn't a letter or digit, like
+// '@' and '$'. Inside a word they stand for letters. At its ends they only do
+// when that spells a banned word, so "$h4rb3rt" is caught and so is the
+// "kerfuffle" in "@kerfuffle".
+func isSymbol(r rune) bool {
+	_, ok := leetspeak[r]
+	return ok && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// trimSymbols splits the symbols at either end off a word.
+func trimSymbols(text string) (word, prefix, suffix string) {
+	word = strings.TrimLeftFunc(text, isSymbol)
+	prefix = text[:len(text)-len(word)]
+	trimmed := strings.TrimRightFunc(word, isSymbol)
+	return trimmed, prefix, word[len(trimmed):]
+}
+
+func ParseAction(s string) (Action, error) {
+	action := Action(strings.ToLower(strings.TrimSpace(s)))
+	if err := action.validate(); err != nil {
+		return "", err
+	}
+	return action, nil
+}
+
+func (a Action) validate() error {
+	switch a {
+	case ActionMask, ActionReject, ActionFlag:
+		return nil
+	}
+	return fmt.Errorf("unknown profanity action %q", a)
+}
+
+func (a Action) stricterThan(other Action) bool {
+	weight := map[Action]int{ActionFlag: 1, ActionMask: 2, ActionReject: 3}
+	return weight[a] > weight[other]
+}
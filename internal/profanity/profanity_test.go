@@ -0,0 +1,171 @@
+package profanity
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	filter := New(StaticSource(DefaultRules), StaticSource{
+		{Word: "crud", Action: ActionReject},
+		{Word: "heck", Action: ActionFlag},
+		{Word: "café", Action: ActionMask},
+	})
+	if err := filter.Reload(context.Background()); err != nil {
+		t.Fatalf("unexpected error loading rules: %v", err)
+	}
+
+	testcases := []struct {
+		name             string
+		msg              string
+		expectedtext     string
+		expectedrejected bool
+		expectedflagged  bool
+	}{
+		{
+			name:         "clean chirp",
+			msg:          "I had something interesting for breakfast",
+			expectedtext: "I had something interesting for breakfast",
+		},
+		{
+			name:         "simple word",
+			msg:          "This is a kerfuffle opinion I need to share with the world",
+			expectedtext: "This is a **** opinion I need to share with the world",
+		},
+		{
+			name:         "mixed case",
+			msg:          "I hear Mastodon is better than Chirpy. sharbert I need to migrate",
+			expectedtext: "I hear Mastodon is better than Chirpy. **** I need to migrate",
+		},
+		{
+			name:         "trailing punctuation",
+			msg:          "Kerfuffle! What a fornax, honestly.",
+			expectedtext: "****! What a ****, honestly.",
+		},
+		{
+			name:         "newlines and tabs",
+			msg:          "first line\nsharbert\tsecond",
+			expectedtext: "first line\n****\tsecond",
+		},
+		{
+			name:         "leetspeak",
+			msg:          "such a k3rfuffl3 and a $h4rb3rt",
+			expectedtext: "such a **** and a ****",
+		},
+		{
+			name:         "mention",
+			msg:          "hey @kerfuffle",
+			expectedtext: "hey @****",
+		},
+		{
+			name:         "trailing symbol",
+			msg:          "what a kerfuffle$",
+			expectedtext: "what a ****$",
+		},
+		{
+			name:         "symbols inside a word",
+			msg:          "sh@rbert",
+			expectedtext: "****",
+		},
+		{
+			name:         "accented letters",
+			msg:          "kérfüffle at the cafe",
+			expectedtext: "**** at the ****",
+		},
+		{
+			name:         "fullwidth letters",
+			msg:          "ｆｏｒｎａｘ",
+			expectedtext: "****",
+		},
+		{
+			name:         "word inside another word",
+			msg:          "kerfuffles are fine",
+			expectedtext: "kerfuffles are fine",
+		},
+		{
+			name:             "rejected word",
+			msg:              "what a load of CRUD",
+			expectedtext:     "what a load of CRUD",
+			expectedrejected: true,
+		},
+		{
+			name:            "flagged word",
+			msg:             "oh heck, kerfuffle",
+			expectedtext:    "oh heck, ****",
+			expectedflagged: true,
+		},
+		{
+			name:         "empty chirp",
+			msg:          "",
+			expectedtext: "",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := filter.Check(tc.msg)
+			if result.Text != tc.expectedtext {
+				t.Errorf("expected text %q, but got %q", tc.expectedtext, result.Text)
+			}
+			if result.Rejected != tc.expectedrejected {
+				t.Errorf("expected rejected %v, but got %v", tc.expectedrejected, result.Rejected)
+			}
+			if result.Flagged != tc.expectedflagged {
+				t.Errorf("expected flagged %v, but got %v", tc.expectedflagged, result.Flagged)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	writeWords := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("unexpected error writing word list: %v", err)
+		}
+	}
+
+	writeWords("# banned words\nkerfuffle\n\nfornax reject\n")
+	filter := New(FileSource{Path: path})
+	if err := filter.Reload(context.Background()); err != nil {
+		t.Fatalf("unexpected error loading rules: %v", err)
+	}
+	if result := filter.Check("kerfuffle fornax"); result.Text != "**** fornax" || !result.Rejected {
+		t.Errorf("unexpected result after first load: %+v", result)
+	}
+
+	writeWords("fornax mask\n")
+	if err := filter.Reload(context.Background()); err != nil {
+		t.Fatalf("unexpected error reloading rules: %v", err)
+	}
+	if result := filter.Check("kerfuffle fornax"); result.Text != "kerfuffle ****" || result.Rejected {
+		t.Errorf("unexpected result after reload: %+v", result)
+	}
+
+	writeWords("fornax explode\n")
+	if err := filter.Reload(context.Background()); err == nil {
+		t.Errorf("expected an error for unknown action, but got none")
+	}
+	if result := filter.Check("fornax"); result.Text != "****" {
+		t.Errorf("expected previous rules to be kept after failed reload, but got %+v", result)
+	}
+}
+
+type failingSource struct{}
+
+func (failingSource) Load(ctx context.Context) ([]Rule, error) {
+	return nil, errors.New("database is down")
+}
+
+func TestReloadWithFailingSource(t *testing.T) {
+	filter := New(StaticSource(DefaultRules), failingSource{})
+	if err := filter.Reload(context.Background()); err == nil {
+		t.Errorf("expected the source's error, but got none")
+	}
+	if result := filter.Check("kerfuffle"); result.Text != "****" {
+		t.Errorf("expected the other sources' rules to be installed, but got %+v", result)
+	}
+}
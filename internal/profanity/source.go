@@ -0,0 +1,83 @@
+package profanity
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+type Source interface {
+	Load(ctx context.Context) ([]Rule, error)
+}
+
+type StaticSource []Rule
+
+func (s StaticSource) Load(ctx context.Context) ([]Rule, error) {
+	return s, nil
+}
+
+// FileSource reads one word per line, optionally followed by an action.
+// Empty lines and lines starting with # are ignored, e.g.
+//
+//	kerfuffle
+//	sharbert reject
+type FileSource struct {
+	Path string
+}
+
+func (s FileSource) Load(ctx context.Context) ([]Rule, error) {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []Rule
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		rule := Rule{Word: fields[0], Action: ActionMask}
+		if len(fields) > 1 {
+			action, err := ParseAction(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", s.Path, lineNumber, err)
+			}
+			rule.Action = action
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+type DBSource struct {
+	Queries database.Querier
+}
+
+func (s DBSource) Load(ctx context.Context) ([]Rule, error) {
+	words, err := s.Queries.GetProfaneWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]Rule, 0, len(words))
+	for _, word := range words {
+		action, err := ParseAction(word.Action)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, Rule{Word: word.Word, Action: action})
+	}
+	return rules, nil
+}
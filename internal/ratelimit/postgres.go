@@ -0,0 +1,31 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table, so the limits
+// are shared by every instance using the same database.
+type PostgresStore struct {
+	Queries database.Querier
+}
+
+func (s PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	bucket, err := s.Queries.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(limit.Requests),
+		Now:   now.UTC(),
+		Rate:  limit.rate(),
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	return result(limit, bucket.Tokens, bucket.Allowed), nil
+}
+
+func (s PostgresStore) Cleanup(ctx context.Context, before time.Time) error {
+	return s.Queries.DeleteStaleRateLimitBuckets(ctx, before.UTC())
+}
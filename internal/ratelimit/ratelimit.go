@@ -0,0 +1,67 @@
+// Package ratelimit is used for throttling requests with token buckets
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Requests requests at once, refilling the bucket evenly over Per.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func PerMinute(requests int) Limit {
+	return Limit{Requests: requests, Per: time.Minute}
+}
+
+// rate returns how many tokens are added to the bucket every second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type Store interface {
+	// Take removes a token from the bucket for key if there is one.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+	// Cleanup forgets buckets that haven't been used since before.
+	Cleanup(ctx context.Context, before time.Time) error
+}
+
+// refill returns the number of tokens in a bucket that had tokens at updatedAt.
+func refill(limit Limit, tokens float64, updatedAt, now time.Time) float64 {
+	elapsed := now.Sub(updatedAt).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(limit.Requests), tokens+elapsed*limit.rate())
+}
+
+func result(limit Limit, tokens float64, allowed bool) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     secondsToDuration((float64(limit.Requests) - tokens) / limit.rate()),
+	}
+	if !allowed {
+		res.RetryAfter = secondsToDuration((1 - tokens) / limit.rate())
+	}
+	return res
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limit := PerMinute(3)
+
+	testcases := []struct {
+		name              string
+		at                time.Duration
+		expectedallowed   bool
+		expectedremaining int
+	}{
+		{name: "first request", at: 0, expectedallowed: true, expectedremaining: 2},
+		{name: "second request", at: time.Second, expectedallowed: true, expectedremaining: 1},
+		{name: "third request", at: 2 * time.Second, expectedallowed: true, expectedremaining: 0},
+		{name: "bucket empty", at: 3 * time.Second, expectedallowed: false, expectedremaining: 0},
+		{name: "one token refilled", at: 23 * time.Second, expectedallowed: true, expectedremaining: 0},
+		{name: "refill capped at burst", at: 10 * time.Minute, expectedallowed: true, expectedremaining: 2},
+	}
+
+	store := NewMemoryStore()
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := store.Take(context.Background(), "key", limit, start.Add(tc.at))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Allowed != tc.expectedallowed {
+				t.Errorf("expected allowed %v, but got %v", tc.expectedallowed, result.Allowed)
+			}
+			if result.Remaining != tc.expectedremaining {
+				t.Errorf("expected remaining %d, but got %d", tc.expectedremaining, result.Remaining)
+			}
+			if !result.Allowed && result.RetryAfter <= 0 {
+				t.Errorf("expected a retry after for a denied request, but got %v", result.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreKeysAndCleanup(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limit := PerMinute(1)
+	store := NewMemoryStore()
+
+	if result, _ := store.Take(context.Background(), "a", limit, now); !result.Allowed {
+		t.Errorf("expected first request for a to be allowed")
+	}
+	if result, _ := store.Take(context.Background(), "b", limit, now); !result.Allowed {
+		t.Errorf("expected buckets to be separate per key")
+	}
+	if result, _ := store.Take(context.Background(), "a", limit, now); result.Allowed {
+		t.Errorf("expected second request for a to be denied")
+	}
+
+	if err := store.Cleanup(context.Background(), now.Add(time.Second)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.buckets) != 0 {
+		t.Errorf("expected idle buckets to be removed, but %d are left", len(store.buckets))
+	}
+}
@@ -0,0 +1,383 @@
+// Package seed is used for filling a database with generated users and
+// chirps, for demos and for reproducing problems that only show up at scale
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/auth"
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+// batchSize is how many rows go in one transaction. Smaller batches keep
+// transactions short; larger ones are faster on Postgres.
+const batchSize = 500
+
+// maxChirpLength is the limit the API enforces.
+const maxChirpLength = 140
+
+// Options sets the scale of the data. Averages are per user; the actual
+// numbers are skewed so that a few users are far more active and far more
+// mentioned than the rest, the way real timelines are.
+type Options struct {
+	Users          int
+	ChirpsPerUser  int
+	FollowsPerUser int
+	BlocksPerUser  int
+	MutesPerUser   int
+	TokensPerUser  int
+	// Period is how far back accounts and chirps go.
+	Period time.Duration
+	// Password is shared by every generated user, so it's hashed only once.
+	Password string
+	// Seed makes the generated content repeatable. IDs and refresh tokens
+	// are still random.
+	Seed uint64
+	Now  time.Time
+}
+
+func (o Options) validate() error {
+	var errs []error
+	if o.Users < 1 {
+		errs = append(errs, errors.New("users must be at least 1"))
+	}
+	for name, n := range map[string]int{"chirps": o.ChirpsPerUser, "follows": o.FollowsPerUser, "blocks": o.BlocksPerUser, "mutes": o.MutesPerUser, "tokens": o.TokensPerUser} {
+		if n < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", name))
+		}
+	}
+	if o.Period <= 0 {
+		errs = append(errs, errors.New("period must be positive"))
+	}
+	if o.Password == "" {
+		errs = append(errs, errors.New("password is required"))
+	}
+	return errors.Join(errs...)
+}
+
+type Stats struct {
+	Users         int
+	Chirps        int
+	Blocks        int
+	Mutes         int
+	RefreshTokens int
+}
+
+type user struct {
+	id          uuid.UUID
+	createdAt   time.Time
+	email       string
+	handle      string
+	displayName string
+	bio         string
+	isChirpyRed bool
+	activity    float64
+	// follows are the users this one mentions. There's no follows table,
+	// so the graph only shows up in chirps.
+	follows []int
+}
+
+// Run generates the data and writes it in batches of transactions, reporting
+// each step to progress. Nothing is deleted first and emails and handles
+// must be unique, so run it against an empty database.
+func Run(ctx context.Context, tx database.Transactor, opts Options, progress io.Writer) (Stats, error) {
+	if err := opts.validate(); err != nil {
+		return Stats{}, err
+	}
+	hashedPassword, err := auth.HashPassword(opts.Password)
+	if err != nil {
+		return Stats{}, err
+	}
+	g := &generator{opts: opts, rand: rand.New(rand.NewPCG(opts.Seed, opts.Seed))}
+	users := g.users()
+	var stats Stats
+
+	err = inBatches(ctx, tx, len(users), func(q database.Querier, i int) error {
+		u := users[i]
+		_, err := q.CreateUser(ctx, database.CreateUserParams{
+			ID:             u.id,
+			CreatedAt:      u.createdAt,
+			UpdatedAt:      u.createdAt,
+			Email:          u.email,
+			HashedPassword: hashedPassword,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = q.UpdateUserProfile(ctx, database.UpdateUserProfileParams{
+			Handle:      sql.NullString{String: u.handle, Valid: true},
+			DisplayName: u.displayName,
+			Bio:         u.bio,
+			UpdatedAt:   u.createdAt,
+			ID:          u.id,
+		})
+		if err != nil {
+			return err
+		}
+		if u.isChirpyRed {
+			_, err = q.UpgradeUser(ctx, database.UpgradeUserParams{IsChirpyRed: true, UpdatedAt: u.createdAt, ID: u.id})
+		}
+		return err
+	})
+	if err != nil {
+		return stats, err
+	}
+	stats.Users = len(users)
+	fmt.Fprintf(progress, "Created %d users\n", stats.Users)
+
+	chirps := g.chirps(users)
+	err = inBatches(ctx, tx, len(chirps), func(q database.Querier, i int) error {
+		_, err := q.CreateChirp(ctx, chirps[i])
+		return err
+	})
+	if err != nil {
+		return stats, err
+	}
+	stats.Chirps = len(chirps)
+	fmt.Fprintf(progress, "Created %d chirps\n", stats.Chirps)
+
+	blocks := g.relations(users, opts.BlocksPerUser)
+	err = inBatches(ctx, tx, len(blocks), func(q database.Querier, i int) error {
+		return q.CreateBlock(ctx, database.CreateBlockParams{BlockerID: blocks[i].from, BlockedID: blocks[i].to, CreatedAt: blocks[i].createdAt})
+	})
+	if err != nil {
+		return stats, err
+	}
+	stats.Blocks = len(blocks)
+	mutes := g.relations(users, opts.MutesPerUser)
+	err = inBatches(ctx, tx, len(mutes), func(q database.Querier, i int) error {
+		return q.CreateMute(ctx, database.CreateMuteParams{MuterID: mutes[i].from, MutedID: mutes[i].to, CreatedAt: mutes[i].createdAt})
+	})
+	if err != nil {
+		return stats, err
+	}
+	stats.Mutes = len(mutes)
+	fmt.Fprintf(progress, "Created %d blocks and %d mutes\n", stats.Blocks, stats.Mutes)
+
+	tokens, err := g.refreshTokens(users)
+	if err != nil {
+		return stats, err
+	}
+	err = inBatches(ctx, tx, len(tokens), func(q database.Querier, i int) error {
+		t := tokens[i]
+		_, err := q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+			Token:     t.Token,
+			CreatedAt: t.CreatedAt,
+			UpdatedAt: t.CreatedAt,
+			ExpiresAt: t.ExpiresAt,
+			UserID:    t.UserID,
+		})
+		if err != nil || !t.RevokedAt.Valid {
+			return err
+		}
+		return q.RevokeToken(ctx, database.RevokeTokenParams{RevokedAt: t.RevokedAt, UpdatedAt: t.RevokedAt.Time, Token: t.Token})
+	})
+	if err != nil {
+		return stats, err
+	}
+	stats.RefreshTokens = len(tokens)
+	fmt.Fprintf(progress, "Created %d refresh tokens\n", stats.RefreshTokens)
+	return stats, nil
+}
+
+// inBatches calls fn for 0 to n-1, committing every batchSize calls.
+func inBatches(ctx context.Context, tx database.Transactor, n int, fn func(q database.Querier, i int) error) error {
+	for start := 0; start < n; start += batchSize {
+		end := min(start+batchSize, n)
+		err := tx.InTx(ctx, func(q database.Querier) error {
+			for i := start; i < end; i++ {
+				if err := fn(q, i); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type generator struct {
+	opts Options
+	rand *rand.Rand
+	// popularity is the cumulative weight of users being followed, blocked
+	// and muted, in user order.
+	popularity []float64
+}
+
+func (g *generator) users() []user {
+	users := make([]user, g.opts.Users)
+	start := g.opts.Now.Add(-g.opts.Period)
+	var total float64
+	for i := range users {
+		first := firstNames[g.rand.IntN(len(firstNames))]
+		last := lastNames[g.rand.IntN(len(lastNames))]
+		handle := fmt.Sprintf("%s_%s%d", strings.ToLower(first), strings.ToLower(last), i)
+		users[i] = user{
+			id: uuid.New(),
+			// Accounts are opened over the first half of the period, so
+			// everyone has some history.
+			createdAt:   g.between(start, start.Add(g.opts.Period/2)),
+			email:       handle + "@example.com",
+			handle:      handle,
+			displayName: first + " " + last,
+			isChirpyRed: g.rand.Float64() < 0.1,
+			// Squaring an exponential gives a long tail: most users post a
+			// little and a few post a lot.
+			activity: math.Pow(g.rand.ExpFloat64(), 2),
+		}
+		if g.rand.Float64() < 0.6 {
+			users[i].bio = bios[g.rand.IntN(len(bios))]
+		}
+		// Zipf-like popularity: the first users are the celebrities.
+		total += 1 / math.Pow(float64(i+1), 1.1)
+		g.popularity = append(g.popularity, total)
+	}
+	for i := range users {
+		users[i].follows = g.pickOthers(i, g.opts.FollowsPerUser)
+	}
+	return users
+}
+
+// pickOthers picks up to n distinct users other than self, favouring the
+// popular ones.
+func (g *generator) pickOthers(self, n int) []int {
+	n = min(n, len(g.popularity)-1)
+	picked := map[int]bool{}
+	var others []int
+	for attempts := 0; len(others) < n && attempts < 10*n; attempts++ {
+		target := g.rand.Float64() * g.popularity[len(g.popularity)-1]
+		i := sort.SearchFloat64s(g.popularity, target)
+		if i == self || i >= len(g.popularity) || picked[i] {
+			continue
+		}
+		picked[i] = true
+		others = append(others, i)
+	}
+	return others
+}
+
+func (g *generator) chirps(users []user) []database.CreateChirpParams {
+	var totalActivity float64
+	for _, u := range users {
+		totalActivity += u.activity
+	}
+	total := float64(g.opts.ChirpsPerUser * len(users))
+	var chirps []database.CreateChirpParams
+	for _, u := range users {
+		n := int(math.Round(total * u.activity / totalActivity))
+		for range n {
+			createdAt := g.between(u.createdAt, g.opts.Now)
+			chirps = append(chirps, database.CreateChirpParams{
+				ID:        uuid.New(),
+				CreatedAt: createdAt,
+				UpdatedAt: createdAt,
+				Body:      g.body(users, u),
+				UserID:    u.id,
+			})
+		}
+	}
+	// Insert in time order, like the real thing, so the indexes look the
+	// same as they would in production.
+	sort.Slice(chirps, func(i, j int) bool { return chirps[i].CreatedAt.Before(chirps[j].CreatedAt) })
+	return chirps
+}
+
+// body writes a chirp of at most 140 characters, sometimes with a mention
+// of someone the author follows and with hashtags for its topic.
+func (g *generator) body(users []user, author user) string {
+	t := topics[g.rand.IntN(len(topics))]
+	body := openers[g.rand.IntN(len(openers))] + " " + t.text + closers[g.rand.IntN(len(closers))]
+	if len(author.follows) > 0 && g.rand.Float64() < 0.3 {
+		mentioned := users[author.follows[g.rand.IntN(len(author.follows))]]
+		body = "@" + mentioned.handle + " " + body
+	}
+	if g.rand.Float64() < 0.6 {
+		body = appendIfFits(body, " #"+t.tag)
+	}
+	if g.rand.Float64() < 0.2 {
+		body = appendIfFits(body, " #"+extraTags[g.rand.IntN(len(extraTags))])
+	}
+	return body
+}
+
+func appendIfFits(body, s string) string {
+	if len(body)+len(s) > maxChirpLength {
+		return body
+	}
+	return body + s
+}
+
+type relation struct {
+	from, to  uuid.UUID
+	createdAt time.Time
+}
+
+// relations gives each user perUser blocks or mutes on average, but most
+// users have few and some have many.
+func (g *generator) relations(users []user, perUser int) []relation {
+	var relations []relation
+	for i, u := range users {
+		n := int(math.Round(float64(perUser) * g.rand.ExpFloat64()))
+		for _, other := range g.pickOthers(i, n) {
+			relations = append(relations, relation{
+				from:      u.id,
+				to:        users[other].id,
+				createdAt: g.between(later(u.createdAt, users[other].createdAt), g.opts.Now),
+			})
+		}
+	}
+	return relations
+}
+
+// refreshTokens gives every user some sessions, a few of which have been
+// revoked or have expired.
+func (g *generator) refreshTokens(users []user) ([]database.RefreshToken, error) {
+	var tokens []database.RefreshToken
+	for _, u := range users {
+		for range g.opts.TokensPerUser {
+			token, err := auth.MakeRefreshToken()
+			if err != nil {
+				return nil, err
+			}
+			createdAt := g.between(u.createdAt, g.opts.Now)
+			t := database.RefreshToken{
+				Token:     token,
+				CreatedAt: createdAt,
+				ExpiresAt: createdAt.Add(60 * 24 * time.Hour),
+				UserID:    u.id,
+			}
+			if g.rand.Float64() < 0.2 {
+				t.RevokedAt = sql.NullTime{Time: g.between(createdAt, g.opts.Now), Valid: true}
+			}
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens, nil
+}
+
+func (g *generator) between(from, to time.Time) time.Time {
+	if !to.After(from) {
+		return from
+	}
+	return from.Add(time.Duration(g.rand.Int64N(int64(to.Sub(from))))).Truncate(time.Microsecond)
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
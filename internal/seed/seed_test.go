@@ -0,0 +1,125 @@
+package seed
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marekbrze/chirpy/internal/auth"
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/memdb"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.New()
+	opts := Options{
+		Users:          40,
+		ChirpsPerUser:  5,
+		FollowsPerUser: 3,
+		BlocksPerUser:  1,
+		MutesPerUser:   1,
+		TokensPerUser:  2,
+		Period:         30 * 24 * time.Hour,
+		Password:       "hunter2",
+		Seed:           7,
+		Now:            time.Now().UTC(),
+	}
+	stats, err := Run(ctx, db, opts, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Users != 40 || stats.Chirps == 0 || stats.RefreshTokens != 80 {
+		t.Errorf("expected 40 users with chirps and 2 tokens each, but got %+v", stats)
+	}
+
+	users, err := db.SearchUsers(ctx, database.SearchUsersParams{Limit: 100})
+	if err != nil || len(users) != 40 {
+		t.Fatalf("expected 40 users, but got %d, %v", len(users), err)
+	}
+	if ok, err := auth.CheckPasswordHash("hunter2", users[0].HashedPassword); err != nil || !ok {
+		t.Errorf("expected the shared password to work, but got %v, %v", ok, err)
+	}
+	handle := regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+	mention := regexp.MustCompile(`@([a-z0-9_]+)`)
+	handles := map[string]bool{}
+	for _, u := range users {
+		if !handle.MatchString(u.Handle.String) {
+			t.Errorf("expected a valid handle, but got %q", u.Handle.String)
+		}
+		handles[u.Handle.String] = true
+	}
+	chirps, err := db.GetAllChirps(ctx, database.GetAllChirpsParams{})
+	if err != nil || len(chirps) != stats.Chirps {
+		t.Fatalf("expected %d chirps, but got %d, %v", stats.Chirps, len(chirps), err)
+	}
+	hashtags := 0
+	for _, c := range chirps {
+		if len(c.Body) > maxChirpLength {
+			t.Errorf("expected at most %d characters, but got %q", maxChirpLength, c.Body)
+		}
+		if c.CreatedAt.After(opts.Now) || c.CreatedAt.Before(opts.Now.Add(-opts.Period)) {
+			t.Errorf("expected the chirp to be within the period, but got %v", c.CreatedAt)
+		}
+		for _, m := range mention.FindAllStringSubmatch(c.Body, -1) {
+			if !handles[m[1]] {
+				t.Errorf("expected mentions of existing users, but got %q", c.Body)
+			}
+		}
+		if strings.Contains(c.Body, "#") {
+			hashtags++
+		}
+	}
+	if hashtags == 0 {
+		t.Error("expected some chirps to have hashtags")
+	}
+
+	// The same seed gives the same users, so seeding twice clashes.
+	if _, err := Run(ctx, db, opts, io.Discard); !database.IsUniqueViolation(err) {
+		t.Errorf("expected a unique violation, but got %v", err)
+	}
+}
+
+func TestRefreshTokensCanBeUsed(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.New()
+	opts := Options{Users: 5, TokensPerUser: 4, Period: time.Hour, Password: "hunter2", Now: time.Now().UTC()}
+	if _, err := Run(ctx, db, opts, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	users, err := db.SearchUsers(ctx, database.SearchUsersParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	revoked := 0
+	for _, u := range users {
+		tokens, err := db.GetUserRefreshTokens(ctx, u.ID)
+		if err != nil || len(tokens) != 4 {
+			t.Fatalf("expected 4 tokens, but got %d, %v", len(tokens), err)
+		}
+		for _, token := range tokens {
+			info, err := db.GetTokenInfo(ctx, token.Token)
+			if err != nil || info.UserID != u.ID {
+				t.Errorf("expected the token to belong to %s, but got %+v, %v", u.ID, info, err)
+			}
+			if token.RevokedAt.Valid {
+				revoked++
+			}
+		}
+	}
+	if revoked == len(users)*4 {
+		t.Error("expected most tokens to still be valid")
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	err := Options{Users: 0, ChirpsPerUser: -1}.validate()
+	for _, expected := range []string{"users", "chirps", "period", "password"} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected an error about %s, but got %v", expected, err)
+		}
+	}
+}
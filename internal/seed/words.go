@@ -0,0 +1,74 @@
+package seed
+
+// Names are at most 10 characters, so a handle made of a first name, a last
+// name and a number stays within the 30 character limit.
+var firstNames = []string{
+	"Ada", "Alan", "Amara", "Ben", "Chloe", "Dev", "Elena", "Farah", "Grace", "Hiro",
+	"Ines", "Jonas", "Kemal", "Lena", "Marco", "Nadia", "Omar", "Priya", "Quinn", "Rosa",
+	"Sam", "Tariq", "Uma", "Victor", "Wen", "Ximena", "Yusuf", "Zoe",
+}
+
+var lastNames = []string{
+	"Abbott", "Baker", "Castillo", "Dubois", "Eriksen", "Fischer", "Garcia", "Hughes", "Ivanova", "Jensen",
+	"Kowalski", "Larsen", "Moreau", "Nakamura", "Okafor", "Patel", "Quinn", "Rossi", "Silva", "Tanaka",
+	"Novak", "Vargas", "Walsh", "Yilmaz", "Zhang",
+}
+
+var bios = []string{
+	"Coffee first, questions later.",
+	"Writing code and bad puns.",
+	"Amateur baker, professional taster.",
+	"Runner. Reader. Occasional gardener.",
+	"Views are my own, and usually about cats.",
+	"Building things on the weekend.",
+	"Chasing sunsets with a film camera.",
+	"Here for the board game takes.",
+}
+
+var openers = []string{
+	"Just tried",
+	"Honestly, I love",
+	"Hot take:",
+	"Can't stop thinking about",
+	"Anyone else into",
+	"Today I learned about",
+	"Reading up on",
+	"Spent the whole weekend on",
+	"Finally got around to",
+	"Unpopular opinion about",
+}
+
+var closers = []string{
+	"",
+	"!",
+	" and it was great.",
+	", would recommend.",
+	". Thoughts?",
+	" again.",
+	". 10/10.",
+	" with friends.",
+}
+
+type topic struct {
+	text string
+	tag  string
+}
+
+var topics = []topic{
+	{"sourdough", "baking"},
+	{"the new coffee place downtown", "coffee"},
+	{"mechanical keyboards", "keyboards"},
+	{"Go generics", "golang"},
+	{"trail running", "running"},
+	{"birdwatching at the lake", "birding"},
+	{"vinyl records", "vinyl"},
+	{"houseplants", "plants"},
+	{"board games", "boardgames"},
+	{"the night sky", "astronomy"},
+	{"retro consoles", "retrogaming"},
+	{"street photography", "photography"},
+	{"learning Italian", "languages"},
+	{"homemade ramen", "cooking"},
+}
+
+var extraTags = []string{"weekend", "tbt", "mondaymotivation", "til", "goals"}
@@ -0,0 +1,46 @@
+package sqlitedb
+
+import (
+	"context"
+	"time"
+
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+const getChirpCountsByDay = `-- name: GetChirpCountsByDay :many
+SELECT date(created_at) AS day, COUNT(*) AS count
+FROM chirps
+WHERE created_at >= $1
+GROUP BY day
+ORDER BY day
+`
+
+// GetChirpCountsByDay uses date() for date_trunc, which SQLite doesn't have.
+// date() returns text, so the day is parsed back into a time.
+func (q *Queries) GetChirpCountsByDay(ctx context.Context, since time.Time) ([]database.GetChirpCountsByDayRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpCountsByDay, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetChirpCountsByDayRow
+	for rows.Next() {
+		var i database.GetChirpCountsByDayRow
+		var day string
+		if err := rows.Scan(&day, &i.Count); err != nil {
+			return nil, err
+		}
+		i.Day, err = time.Parse(time.DateOnly, day)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
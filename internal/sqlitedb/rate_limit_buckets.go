@@ -0,0 +1,45 @@
+package sqlitedb
+
+import (
+	"context"
+
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+const takeRateLimitToken = `-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at)
+VALUES ($1, $2 - 1, true, $3)
+ON CONFLICT (key) DO UPDATE
+SET (tokens, allowed, updated_at) = (
+    SELECT
+        CASE
+            WHEN refilled.tokens >= 1 THEN refilled.tokens - 1
+            ELSE refilled.tokens
+        END,
+        refilled.tokens >= 1,
+        excluded.updated_at
+    FROM (
+        SELECT MIN(
+            $2,
+            rate_limit_buckets.tokens
+            + MAX(0, julianday(excluded.updated_at) - julianday(rate_limit_buckets.updated_at))
+            * 86400 * $4
+        ) AS tokens
+    ) AS refilled
+)
+RETURNING tokens, allowed
+`
+
+// TakeRateLimitToken replaces the Postgres casts, LEAST, GREATEST and
+// EXTRACT(EPOCH) with their SQLite equivalents.
+func (q *Queries) TakeRateLimitToken(ctx context.Context, arg database.TakeRateLimitTokenParams) (database.TakeRateLimitTokenRow, error) {
+	row := q.db.QueryRowContext(ctx, takeRateLimitToken,
+		arg.Key,
+		arg.Burst,
+		arg.Now,
+		arg.Rate,
+	)
+	var i database.TakeRateLimitTokenRow
+	err := row.Scan(&i.Tokens, &i.Allowed)
+	return i, err
+}
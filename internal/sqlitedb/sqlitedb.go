@@ -0,0 +1,91 @@
+// Package sqlitedb is used for running chirpy on SQLite instead of Postgres
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marekbrze/chirpy/internal/database"
+	_ "modernc.org/sqlite"
+)
+
+const scheme = "sqlite:"
+
+// IsURL reports whether a database URL such as sqlite:chirpy.db or
+// sqlite::memory: points at SQLite.
+func IsURL(databaseURL string) bool {
+	return strings.HasPrefix(databaseURL, scheme)
+}
+
+// Open turns on foreign keys, which SQLite leaves off by default, and keeps a
+// single connection because SQLite only allows one writer at a time.
+func Open(databaseURL string) (*sql.DB, error) {
+	path := strings.TrimPrefix(strings.TrimPrefix(databaseURL, scheme), "//")
+	query := url.Values{}
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "busy_timeout(5000)")
+	query.Set("_time_format", "sqlite")
+	db, err := sql.Open("sqlite", "file:"+path+"?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// Queries runs the generated Postgres queries, which are portable SQL, and
+// replaces the ones that use Postgres-only syntax.
+type Queries struct {
+	*database.Queries
+	db database.DBTX
+}
+
+var _ database.Querier = (*Queries)(nil)
+
+func New(db database.DBTX) *Queries {
+	db = conn{db}
+	return &Queries{
+		Queries: database.New(db),
+		db:      db,
+	}
+}
+
+// conn stores every time in UTC to the microsecond, like a Postgres
+// TIMESTAMP column. Times are kept as text in SQLite, so a fixed zone is what
+// makes comparing them in SQL work.
+type conn struct {
+	database.DBTX
+}
+
+func (c conn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.DBTX.ExecContext(ctx, query, normalize(args)...)
+}
+
+func (c conn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.DBTX.QueryContext(ctx, query, normalize(args)...)
+}
+
+func (c conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return c.DBTX.QueryRowContext(ctx, query, normalize(args)...)
+}
+
+func normalize(args []interface{}) []interface{} {
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case time.Time:
+			args[i] = timestamp(arg)
+		case sql.NullTime:
+			if arg.Valid {
+				args[i] = timestamp(arg.Time)
+			}
+		}
+	}
+	return args
+}
+
+func timestamp(t time.Time) time.Time {
+	return t.UTC().Round(time.Microsecond)
+}
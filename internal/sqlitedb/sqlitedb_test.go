@@ -0,0 +1,132 @@
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/database/databasetest"
+	"github.com/marekbrze/chirpy/internal/migrate"
+)
+
+func TestConformance(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T) database.Querier {
+		return New(openTestDB(t))
+	})
+}
+
+func TestTransactor(t *testing.T) {
+	databasetest.RunTransactor(t, func(t *testing.T) (database.Querier, database.Transactor) {
+		db := openTestDB(t)
+		return New(db), newTestTransactor(db)
+	})
+}
+
+// busyError looks like the driver's SQLITE_BUSY error.
+type busyError struct{}
+
+func (busyError) Error() string { return "database is locked" }
+func (busyError) Code() int     { return 5 }
+
+func TestTransactorRetriesBusyDatabase(t *testing.T) {
+	tx := newTestTransactor(openTestDB(t))
+	testcases := []struct {
+		name             string
+		failures         int
+		expectedattempts int
+		expectederr      bool
+	}{
+		{name: "no failures", failures: 0, expectedattempts: 1},
+		{name: "one failure", failures: 1, expectedattempts: 2},
+		{name: "always busy", failures: 10, expectedattempts: 3, expectederr: true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			err := tx.InTx(context.Background(), func(q database.Querier) error {
+				attempts++
+				if attempts <= tc.failures {
+					return busyError{}
+				}
+				return nil
+			})
+			if attempts != tc.expectedattempts {
+				t.Errorf("expected %d attempts, but got %d", tc.expectedattempts, attempts)
+			}
+			if (err != nil) != tc.expectederr {
+				t.Errorf("expected error to be %v, but got %v", tc.expectederr, err)
+			}
+		})
+	}
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := Open("sqlite::memory:")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrator, err := migrate.NewSQLite(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("unexpected error migrating: %v", err)
+	}
+	return db
+}
+
+func newTestTransactor(db *sql.DB) database.Transactor {
+	return database.NewTransactor(db, nil, func(tx database.DBTX) database.Querier {
+		return New(tx)
+	})
+}
+
+func TestIsURL(t *testing.T) {
+	testcases := []struct {
+		url      string
+		expected bool
+	}{
+		{url: "sqlite:chirpy.db", expected: true},
+		{url: "sqlite:///var/lib/chirpy/chirpy.db", expected: true},
+		{url: "sqlite::memory:", expected: true},
+		{url: "postgres://localhost/chirpy", expected: false},
+		{url: "", expected: false},
+	}
+	for _, tc := range testcases {
+		if got := IsURL(tc.url); got != tc.expected {
+			t.Errorf("expected IsURL(%q) to be %v, but got %v", tc.url, tc.expected, got)
+		}
+	}
+}
+
+func TestAuditEventsAreAppendOnly(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	_, err := db.ExecContext(ctx, `INSERT INTO audit_events (id, created_at, action, ip, user_agent, metadata) VALUES ('6b6d2c1e-8f0a-4c4e-9a53-0c1b5e2d7f10', '2026-01-01 00:00:00', 'user.login', '', '', '{}')`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE audit_events SET action = 'user.logout'`); err == nil {
+		t.Error("expected updating an audit event to fail")
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO audit_events (id, created_at, action, ip, user_agent, metadata) VALUES ('0f2e3d4c-1b2a-4c3d-8e4f-5a6b7c8d9e01', '2026-01-01 00:00:01', 'user.login_failed', '192.0.2.1', 'curl/8.0', '{"email":"walt@example.com","reason":"incorrect_login"}')`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	const failedLogin = `UPDATE audit_events SET ip = '', user_agent = '', metadata = ? WHERE id = '0f2e3d4c-1b2a-4c3d-8e4f-5a6b7c8d9e01'`
+	if _, err := db.ExecContext(ctx, failedLogin, `{"email_sha256":"a1","reason":"something_else"}`); err == nil {
+		t.Error("expected changing the rest of the metadata to fail")
+	}
+	if _, err := db.ExecContext(ctx, failedLogin, `{"email":"walt@example.com","reason":"incorrect_login"}`); err == nil {
+		t.Error("expected keeping the email to fail")
+	}
+	if _, err := db.ExecContext(ctx, failedLogin, []byte(`{"email_sha256":"a1","reason":"incorrect_login"}`)); err != nil {
+		t.Errorf("expected pseudonymizing an audit event to work, but got %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM audit_events`); err == nil {
+		t.Error("expected deleting an audit event to fail")
+	}
+}
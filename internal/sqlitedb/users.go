@@ -0,0 +1,56 @@
+package sqlitedb
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+const getAuthors = `-- name: GetAuthors :many
+SELECT
+    users.id,
+    users.handle,
+    users.display_name,
+    EXISTS (
+        SELECT 1 FROM avatars WHERE avatars.user_id = users.id
+    ) AS has_avatar
+FROM users
+WHERE users.id IN (SELECT value FROM json_each($1))
+ORDER BY users.id
+`
+
+// GetAuthors passes the IDs as a JSON array because SQLite has no arrays to
+// replace ANY($1::UUID []) with.
+func (q *Queries) GetAuthors(ctx context.Context, ids []uuid.UUID) ([]database.GetAuthorsRow, error) {
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.db.QueryContext(ctx, getAuthors, string(idsJSON))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetAuthorsRow
+	for rows.Next() {
+		var i database.GetAuthorsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Handle,
+			&i.DisplayName,
+			&i.HasAvatar,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/marekbrze/chirpy/internal/database"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/marekbrze/chirpy/internal/tracing"
+
+// DB traces every query sent through it. sqlc starts each query with a
+// "-- name: Query :kind" comment, which becomes the span name.
+type DB struct {
+	db     database.DBTX
+	system attribute.KeyValue
+	tracer trace.Tracer
+}
+
+// WrapDB takes the db.system attribute for the database behind db, such as
+// semconv.DBSystemPostgreSQL.
+func WrapDB(db database.DBTX, system attribute.KeyValue) *DB {
+	return &DB{
+		db:     db,
+		system: system,
+		tracer: otel.Tracer(tracerName),
+	}
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := d.start(ctx, query)
+	defer span.End()
+	result, err := d.db.ExecContext(ctx, query, args...)
+	recordError(span, err)
+	return result, err
+}
+
+func (d *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := d.start(ctx, query)
+	defer span.End()
+	stmt, err := d.db.PrepareContext(ctx, query)
+	recordError(span, err)
+	return stmt, err
+}
+
+// QueryContext can't wrap the *sql.Rows it returns, so its span ends once the
+// first row is ready and doesn't cover reading the rest.
+func (d *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := d.start(ctx, query)
+	defer span.End()
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	recordError(span, err)
+	return rows, err
+}
+
+// QueryRowContext can't see scan errors, so its span only covers running the
+// query.
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := d.start(ctx, query)
+	defer span.End()
+	row := d.db.QueryRowContext(ctx, query, args...)
+	recordError(span, row.Err())
+	return row
+}
+
+func (d *DB) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	name := queryName(query)
+	return d.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			d.system,
+			semconv.DBQueryText(query),
+			attribute.String("db.operation.name", name),
+		),
+	)
+}
+
+func queryName(query string) string {
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(query), "\n")
+	if rest, ok := strings.CutPrefix(firstLine, "-- name: "); ok {
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+	return "query"
+}
+
+func recordError(span trace.Span, err error) {
+	if err == nil || err == sql.ErrNoRows {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,55 @@
+// Package validate is used for checking decoded request bodies field by field
+package validate
+
+import (
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/marekbrze/chirpy/internal/problem"
+)
+
+// Validator collects every broken rule instead of stopping at the first, so
+// clients can fix all of them in one go.
+type Validator struct {
+	Errors []problem.FieldError
+}
+
+// Check records message for field when ok is false. Only the first broken
+// rule of a field is kept.
+func (v *Validator) Check(ok bool, field, message string) {
+	if ok {
+		return
+	}
+	for _, fieldErr := range v.Errors {
+		if fieldErr.Field == field {
+			return
+		}
+	}
+	v.Errors = append(v.Errors, problem.FieldError{Field: field, Message: message})
+}
+
+// Err returns nil when every rule passed, and an invalid-input problem
+// listing the fields otherwise.
+func (v *Validator) Err() error {
+	if len(v.Errors) == 0 {
+		return nil
+	}
+	return problem.Invalid(v.Errors...)
+}
+
+func NotBlank(s string) bool {
+	return strings.TrimSpace(s) != ""
+}
+
+// MaxLength counts characters, not bytes.
+func MaxLength(s string, n int) bool {
+	return utf8.RuneCountInString(s) <= n
+}
+
+// Email accepts a bare address like walt@example.com, without a display name
+// or angle brackets.
+func Email(s string) bool {
+	address, err := mail.ParseAddress(s)
+	return err == nil && address.Address == s
+}
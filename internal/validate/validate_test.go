@@ -0,0 +1,40 @@
+package validate
+
+import "testing"
+
+func TestValidator(t *testing.T) {
+	v := &Validator{}
+	v.Check(NotBlank(" "), "email", "is required")
+	v.Check(Email(" "), "email", "must be an email address")
+	v.Check(MaxLength("żółw", 4), "body", "is too long")
+	v.Check(NotBlank(""), "password", "is required")
+	if len(v.Errors) != 2 {
+		t.Fatalf("expected 2 errors, but got %+v", v.Errors)
+	}
+	if v.Errors[0].Message != "is required" || v.Errors[1].Field != "password" {
+		t.Errorf("expected the first broken rule of email and password, but got %+v", v.Errors)
+	}
+	if v.Err() == nil {
+		t.Error("expected an error, but got nil")
+	}
+	if err := (&Validator{}).Err(); err != nil {
+		t.Errorf("expected no error, but got %v", err)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	testcases := []struct {
+		email    string
+		expected bool
+	}{
+		{email: "walt@example.com", expected: true},
+		{email: "walt", expected: false},
+		{email: "Walt <walt@example.com>", expected: false},
+		{email: "", expected: false},
+	}
+	for _, tc := range testcases {
+		if got := Email(tc.email); got != tc.expected {
+			t.Errorf("expected Email(%q) to be %v, but got %v", tc.email, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDPattern limits client supplied request IDs to something safe to
+// log and echo back.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type requestLogKey struct{}
+
+// requestLog is shared by everything handling one request, so handlers can
+// add the user once they know it and the final log line includes it.
+type requestLog struct {
+	mu     sync.Mutex
+	logger *slog.Logger
+	userID uuid.UUID
+}
+
+func newLogger(out io.Writer, format, level string) *slog.Logger {
+	options := &slog.HandlerOptions{Level: parseLogLevel(level)}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(out, options))
+	}
+	return slog.New(slog.NewTextHandler(out, options))
+}
+
+func parseLogLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// middlewareLogging gives every request an ID, taken from X-Request-ID when the
+// client sends a sensible one, and logs the request once it's done.
+func middlewareLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		requestID := r.Header.Get("X-Request-ID")
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		logger := slog.Default().With(
+			slog.String("request_id", requestID),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+		)
+		if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.HasTraceID() {
+			logger = logger.With(slog.String("trace_id", spanContext.TraceID().String()))
+		}
+		reqLog := &requestLog{logger: logger}
+		r = r.WithContext(context.WithValue(r.Context(), requestLogKey{}, reqLog))
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		logger = loggerFrom(r.Context())
+		level := slog.LevelInfo
+		if recorder.status >= 500 {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(r.Context(), level, "request completed",
+			slog.String("route", r.Pattern),
+			slog.Int("status", recorder.status),
+			slog.Duration("duration", time.Since(start)),
+		)
+	})
+}
+
+// loggerFrom returns the request scoped logger, or the default logger outside
+// of a request.
+func loggerFrom(ctx context.Context) *slog.Logger {
+	reqLog, ok := ctx.Value(requestLogKey{}).(*requestLog)
+	if !ok {
+		return slog.Default()
+	}
+	reqLog.mu.Lock()
+	defer reqLog.mu.Unlock()
+	if reqLog.userID != uuid.Nil {
+		return reqLog.logger.With(slog.String("user_id", reqLog.userID.String()))
+	}
+	return reqLog.logger
+}
+
+func setRequestUser(ctx context.Context, userID uuid.UUID) {
+	reqLog, ok := ctx.Value(requestLogKey{}).(*requestLog)
+	if !ok {
+		return
+	}
+	reqLog.mu.Lock()
+	reqLog.userID = userID
+	reqLog.mu.Unlock()
+}
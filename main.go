@@ -1,57 +1,224 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
-	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/config"
+	"github.com/marekbrze/chirpy/internal/fixtures"
+	"github.com/marekbrze/chirpy/internal/health"
+	"github.com/marekbrze/chirpy/internal/profanity"
+	"github.com/marekbrze/chirpy/internal/ratelimit"
+	"github.com/marekbrze/chirpy/internal/tracing"
 )
 
+var subcommands = map[string]func(ctx context.Context, args []string) error{
+	"migrate": runMigrate,
+	"seed":    runSeed,
+}
+
 func main() {
 	err := godotenv.Load(".env")
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		log.Fatal("Error loading .env file")
 	}
-	dbURL := os.Getenv("CHIRPY_URL")
-	db, err := sql.Open("postgres", dbURL)
+	if len(os.Args) > 1 && subcommands[os.Args[1]] != nil {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := subcommands[os.Args[1]](ctx, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Print(config.Usage())
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if cfg.PrintConfig {
+		fmt.Print(cfg.Redacted())
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	slog.SetDefault(newLogger(os.Stderr, cfg.LogFormat, cfg.LogLevel))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	backgroundWorkers := &workers{}
+	shutdownTracing, err := tracing.Setup(ctx, cfg.TraceExporter, cfg.ServiceName)
+	if err != nil {
+		slog.Error("Couldn't set up tracing", "error", err)
+		os.Exit(1)
+	}
+	db, migrator, err := openDatabase(cfg.DatabaseURL)
 	if err != nil {
-		log.Fatal("Couldn't connect to database")
+		slog.Error("Couldn't connect to database", "error", err)
+		os.Exit(1)
+	}
+	if err := prepareSchema(ctx, migrator, cfg.AutoMigrate); err != nil {
+		slog.Error("Database schema isn't ready", "error", err)
+		os.Exit(1)
+	}
+	dbQueries := newQueries(cfg.DatabaseURL, db)
+	profanitySources := []profanity.Source{profanity.StaticSource(profanity.DefaultRules)}
+	if cfg.ProfanityFile != "" {
+		profanitySources = append(profanitySources, profanity.FileSource{Path: cfg.ProfanityFile})
+	}
+	profanitySources = append(profanitySources, profanity.DBSource{Queries: dbQueries})
+	profanityFilter := profanity.New(profanitySources...)
+	if err := profanityFilter.Reload(ctx); err != nil {
+		slog.Error("Failed to load profanity list", "error", err)
+	}
+	backgroundWorkers.every(ctx, "profanity_reload", cfg.ProfanityReload, func(ctx context.Context) {
+		if err := profanityFilter.Reload(ctx); err != nil {
+			slog.Error("Failed to reload profanity list", "error", err)
+		}
+	})
+	// Validate has already checked the CIDRs.
+	trustedProxies, _ := cfg.TrustedProxyPrefixes()
+	var rateLimiter ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimitStore == "postgres" {
+		rateLimiter = ratelimit.PostgresStore{Queries: dbQueries}
 	}
-	dbQueries := database.New(db)
 	apiCfg := apiConfig{
-		fileserverhits: atomic.Int32{},
-		dbQueries:      dbQueries,
-		platform:       os.Getenv("PLATFORM"),
-		jwtSecret:      os.Getenv("JWT_SECRET"),
-		apiKey:         os.Getenv("POLKA_KEY"),
+		fileserverhits:             atomic.Int32{},
+		dbQueries:                  dbQueries,
+		tx:                         newTransactor(cfg.DatabaseURL, db),
+		platform:                   cfg.Platform,
+		jwtSecret:                  cfg.JWTSecret,
+		apiKey:                     cfg.PolkaKey,
+		accessTokenTTL:             cfg.AccessTokenTTL,
+		refreshTokenTTL:            cfg.RefreshTokenTTL,
+		chirpRestoreGracePeriod:    cfg.ChirpRestoreGracePeriod,
+		chirpRetention:             cfg.ChirpRetention,
+		accountDeletionGracePeriod: cfg.AccountDeletionGracePeriod,
+		profanity:                  profanityFilter,
+		rateLimiter:                rateLimiter,
+		trustedProxies:             trustedProxies,
+		metrics:                    newMetrics(db),
+		fixtures:                   fixtures.Dir{FS: os.DirFS(cfg.FixturesDir)},
+		snapshots:                  fixtures.NewSnapshots(),
+	}
+	backgroundWorkers.every(ctx, "purge_deleted_chirps", time.Hour, apiCfg.purgeDeletedChirps)
+	backgroundWorkers.every(ctx, "purge_deleted_users", time.Hour, apiCfg.purgeDeletedUsers)
+	backgroundWorkers.every(ctx, "rate_limit_cleanup", 10*time.Minute, apiCfg.cleanupRateLimits)
+	readinessChecks := []health.Check{
+		health.Ping(db),
+		health.SchemaVersion(migrator.Current, migrator.Latest()),
 	}
+	apiCfg.readiness = health.NewChecker(cfg.HealthCheckTimeout, cfg.HealthCacheTTL, append(readinessChecks, backgroundWorkers.checks()...)...)
+	// Probes, metric scrapes and static files are cheap and come often from a
+	// few addresses, so they're mounted outside the global limit. Every
+	// other route goes through it to the same mux.
+	limits := newRateLimits(cfg)
+	routes := apiCfg.routes(limits)
 	serverMux := http.NewServeMux()
-	serverMux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(".")))))
-	serverMux.HandleFunc("GET /api/healthz", healthCheck)
-	serverMux.HandleFunc("GET /admin/metrics", apiCfg.getNumberOfHits)
-	serverMux.HandleFunc("POST /admin/reset", apiCfg.reset)
-	serverMux.HandleFunc("POST /api/users", apiCfg.addUser)
-	serverMux.HandleFunc("POST /api/polka/webhooks", apiCfg.upgradeUser)
-	serverMux.HandleFunc("PUT /api/users", apiCfg.updateUser)
-	serverMux.HandleFunc("POST /api/login", apiCfg.loginUser)
-	serverMux.HandleFunc("POST /api/chirps", apiCfg.addChirp)
-	serverMux.HandleFunc("POST /api/refresh", apiCfg.refreshToken)
-	serverMux.HandleFunc("POST /api/revoke", apiCfg.revokeToken)
-	serverMux.HandleFunc("GET /api/chirps", apiCfg.getAllChirps)
-	serverMux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.getChirpByID)
-	serverMux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.deleteChirp)
+	for _, pattern := range []string{"GET /api/livez", "GET /api/readyz", "GET /api/healthz", "GET /metrics", "/app/"} {
+		serverMux.Handle(pattern, routes)
+	}
+	serverMux.Handle("/", apiCfg.middlewareRateLimit("global", limits.global, routes))
 	server := &http.Server{
-		Handler: serverMux,
-		Addr:    ":8080",
+		Handler:           middlewareTracing(apiCfg.middlewareClientIP(middlewareLogging(apiCfg.metrics.middleware(middlewareRouteSpanName(serverMux))))),
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+		Addr:              cfg.Addr,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
 	}
-	fmt.Println("Server ready")
-	if err := server.ListenAndServe(); err != nil {
-		log.Fatalf("There was a problem %v", err)
+	slog.Info("Server ready", "addr", cfg.Addr, "platform", cfg.Platform)
+	serveErr := apiCfg.serve(ctx, server, cfg.ShutdownDelay, cfg.ShutdownTimeout)
+	stop()
+	backgroundWorkers.wait()
+	if err := db.Close(); err != nil {
+		slog.Error("Failed to close database", "error", err)
 	}
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+	if serveErr != nil {
+		slog.Error("There was a problem", "error", serveErr)
+		os.Exit(1)
+	}
+	slog.Info("Server stopped")
+}
+
+func (cfg *apiConfig) routes(limits rateLimits) *http.ServeMux {
+	serverMux := http.NewServeMux()
+	serverMux.Handle("/app/", cfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(".")))))
+	serverMux.HandleFunc("GET /api/healthz", cfg.healthCheck)
+	serverMux.HandleFunc("GET /api/livez", liveness)
+	serverMux.HandleFunc("GET /api/readyz", cfg.readinessCheck)
+	serverMux.HandleFunc("GET /admin/{$}", cfg.dashboard)
+	serverMux.Handle("GET /admin/metrics", http.RedirectHandler("/admin/", http.StatusMovedPermanently))
+	serverMux.Handle("GET /admin/assets/", dashboardAssets())
+	serverMux.HandleFunc("GET /admin/login", cfg.dashboardLoginForm)
+	serverMux.Handle("POST /admin/login", cfg.middlewareRateLimit("login", limits.login, http.HandlerFunc(cfg.dashboardLogin)))
+	serverMux.HandleFunc("POST /admin/logout", cfg.dashboardLogout)
+	serverMux.HandleFunc("POST /admin/dashboard/reports/{reportID}/dismiss", cfg.dashboardDismissReport)
+	serverMux.HandleFunc("POST /admin/dashboard/chirps/{chirpID}/hide", cfg.dashboardHideChirp)
+	serverMux.Handle("GET /metrics", cfg.metrics.handler())
+	serverMux.HandleFunc("POST /admin/reset", cfg.reset)
+	serverMux.HandleFunc("GET /admin/fixtures", cfg.listFixtures)
+	serverMux.HandleFunc("POST /admin/fixtures/{name}", cfg.applyFixture)
+	serverMux.HandleFunc("POST /admin/snapshots/{name}", cfg.takeSnapshot)
+	serverMux.HandleFunc("GET /admin/snapshots/{name}", cfg.getSnapshot)
+	serverMux.HandleFunc("POST /admin/snapshots/{name}/restore", cfg.restoreSnapshot)
+	serverMux.Handle("POST /api/users", cfg.middlewareRateLimit("signup", limits.signup, http.HandlerFunc(cfg.addUser)))
+	serverMux.HandleFunc("POST /api/polka/webhooks", cfg.upgradeUser)
+	serverMux.HandleFunc("PUT /api/users", cfg.updateUser)
+	serverMux.HandleFunc("PATCH /api/users", cfg.patchUser)
+	serverMux.HandleFunc("DELETE /api/users", cfg.deleteUser)
+	serverMux.Handle("POST /api/users/export", cfg.middlewareRateLimit("export", limits.export, http.HandlerFunc(cfg.exportUser)))
+	serverMux.HandleFunc("GET /api/users/me/security-log", cfg.getSecurityLog)
+	serverMux.HandleFunc("GET /api/users/{handle}", cfg.getUserProfile)
+	serverMux.HandleFunc("PUT /api/users/avatar", cfg.uploadAvatar)
+	serverMux.HandleFunc("DELETE /api/users/avatar", cfg.deleteAvatar)
+	serverMux.HandleFunc("GET /api/users/{userID}/avatar", cfg.getAvatar)
+	serverMux.HandleFunc("POST /api/users/{userID}/block", cfg.blockUser)
+	serverMux.HandleFunc("DELETE /api/users/{userID}/block", cfg.unblockUser)
+	serverMux.HandleFunc("POST /api/users/{userID}/mute", cfg.muteUser)
+	serverMux.HandleFunc("DELETE /api/users/{userID}/mute", cfg.unmuteUser)
+	serverMux.Handle("POST /api/login", cfg.middlewareRateLimit("login", limits.login, http.HandlerFunc(cfg.loginUser)))
+	serverMux.Handle("POST /api/chirps", cfg.middlewareRateLimit("chirps", limits.chirps, http.HandlerFunc(cfg.addChirp)))
+	serverMux.Handle("POST /api/refresh", cfg.middlewareRateLimit("refresh", limits.refresh, http.HandlerFunc(cfg.refreshToken)))
+	serverMux.HandleFunc("POST /api/revoke", cfg.revokeToken)
+	serverMux.HandleFunc("GET /api/chirps", cfg.getAllChirps)
+	serverMux.HandleFunc("GET /api/chirps/{chirpID}", cfg.getChirpByID)
+	serverMux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.deleteChirp)
+	serverMux.HandleFunc("POST /api/chirps/{chirpID}/restore", cfg.restoreChirp)
+	serverMux.Handle("POST /api/chirps/{chirpID}/report", cfg.middlewareRateLimit("reports", limits.reports, http.HandlerFunc(cfg.reportChirp)))
+	serverMux.HandleFunc("GET /admin/reports", cfg.getOpenReports)
+	serverMux.HandleFunc("POST /admin/reports/{reportID}/dismiss", cfg.dismissReport)
+	serverMux.HandleFunc("POST /admin/reports/{reportID}/suspend-author", cfg.suspendReportedAuthor)
+	serverMux.HandleFunc("GET /admin/users", cfg.listUsers)
+	serverMux.HandleFunc("GET /admin/users/{userID}", cfg.getUserDetails)
+	serverMux.HandleFunc("POST /admin/users/{userID}/suspend", cfg.suspendUserByAdmin)
+	serverMux.HandleFunc("POST /admin/users/{userID}/unsuspend", cfg.unsuspendUser)
+	serverMux.HandleFunc("POST /admin/users/{userID}/require-password-reset", cfg.requirePasswordReset)
+	serverMux.HandleFunc("POST /admin/users/{userID}/grant-chirpy-red", cfg.grantChirpyRed)
+	serverMux.HandleFunc("POST /admin/users/{userID}/revoke-chirpy-red", cfg.revokeChirpyRed)
+	serverMux.HandleFunc("POST /admin/chirps/{chirpID}/hide", cfg.hideChirp)
+	serverMux.HandleFunc("POST /admin/chirps/{chirpID}/restore", cfg.restoreHiddenChirp)
+	serverMux.HandleFunc("GET /admin/audit", cfg.listAuditEvents)
+	return serverMux
 }
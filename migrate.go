@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/marekbrze/chirpy/internal/config"
+	"github.com/marekbrze/chirpy/internal/migrate"
+)
+
+const migrateUsage = "Usage: chirpy migrate up|down|status [flags]\n"
+
+// runMigrate handles the migrate subcommand. Only the database URL is
+// needed, so the rest of the configuration isn't validated.
+func runMigrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	command := args[0]
+	switch command {
+	case "up", "down", "status":
+	case "-h", "-help", "--help":
+		fmt.Print(migrateUsage)
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", command, migrateUsage)
+	}
+	cfg, err := config.Load(args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Print(migrateUsage, config.Usage())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if cfg.DatabaseURL == "" {
+		return errors.New("database_url is required (set CHIRPY_URL or -database-url)")
+	}
+	db, migrator, err := openDatabase(cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch command {
+	case "up":
+		results, err := migrator.Up(ctx)
+		for _, result := range results {
+			fmt.Println(result)
+		}
+		if err == nil && len(results) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		return err
+	case "down":
+		result, err := migrator.Down(ctx)
+		if result != nil {
+			fmt.Println(result)
+		}
+		return err
+	default:
+		return migrator.Status(ctx, os.Stdout)
+	}
+}
+
+// prepareSchema applies pending migrations when autoMigrate is set, then
+// refuses to start against a schema older than the queries expect.
+func prepareSchema(ctx context.Context, migrator *migrate.Migrator, autoMigrate bool) error {
+	if autoMigrate {
+		results, err := migrator.Up(ctx)
+		for _, result := range results {
+			logger := slog.With("migration", result.Source.Path, "duration", result.Duration)
+			if result.Error != nil {
+				logger.Error("Migration failed", "error", result.Error)
+				continue
+			}
+			logger.Info("Applied migration")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := migrator.Check(ctx); err != nil {
+		if errors.Is(err, migrate.ErrSchemaBehind) {
+			return fmt.Errorf("%w; run chirpy migrate up or start with -auto-migrate", err)
+		}
+		return err
+	}
+	return nil
+}
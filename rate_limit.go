@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marekbrze/chirpy/internal/auth"
+	"github.com/marekbrze/chirpy/internal/config"
+	"github.com/marekbrze/chirpy/internal/problem"
+	"github.com/marekbrze/chirpy/internal/ratelimit"
+)
+
+// rateLimits holds the limit for every bucket name.
+type rateLimits struct {
+	global  ratelimit.Limit
+	signup  ratelimit.Limit
+	login   ratelimit.Limit
+	export  ratelimit.Limit
+	chirps  ratelimit.Limit
+	refresh ratelimit.Limit
+	reports ratelimit.Limit
+}
+
+func newRateLimits(cfg config.Config) rateLimits {
+	return rateLimits{
+		global:  ratelimit.PerMinute(cfg.RateLimitGlobal),
+		signup:  ratelimit.PerMinute(cfg.RateLimitSignup),
+		login:   ratelimit.PerMinute(cfg.RateLimitLogin),
+		export:  ratelimit.PerMinute(cfg.RateLimitExport),
+		chirps:  ratelimit.PerMinute(cfg.RateLimitChirps),
+		refresh: ratelimit.PerMinute(cfg.RateLimitRefresh),
+		reports: ratelimit.PerMinute(cfg.RateLimitReports),
+	}
+}
+
+// middlewareRateLimit throttles next per user when the request carries a
+// valid JWT and per client IP otherwise. Buckets are separate for every name.
+func (cfg *apiConfig) middlewareRateLimit(name string, limit ratelimit.Limit, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := name + ":" + cfg.rateLimitKey(r)
+		result, err := cfg.rateLimiter.Take(r.Context(), key, limit, time.Now())
+		if err != nil {
+			// Don't take the API down with the rate limit store.
+			loggerFrom(r.Context()).Error("Failed to check rate limit", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			respondWithError(w, r, problem.New(problem.TooManyRequests, fmt.Sprintf("Try again in %d seconds", ceilSeconds(result.RetryAfter))))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (cfg *apiConfig) rateLimitKey(r *http.Request) string {
+	if headerToken, err := auth.GetBearerToken(r.Header); err == nil {
+		if userID, err := auth.ValidateJWT(headerToken, cfg.jwtSecret); err == nil {
+			return "user:" + userID.String()
+		}
+	}
+	return "ip:" + clientIP(r)
+}
+
+type clientIPKey struct{}
+
+// middlewareClientIP works out the client's address once per request. It
+// must run outside middlewareLogging, which needs the request the mux sees.
+func (cfg *apiConfig) middlewareClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := resolveClientIP(r, cfg.trustedProxies)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+	})
+}
+
+// clientIP is the address set by middlewareClientIP, or the peer's address
+// when the middleware didn't run.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return resolveClientIP(r, nil)
+}
+
+// resolveClientIP believes X-Forwarded-For only when the peer is a trusted
+// proxy, since anyone else can put whatever they like in it. The header is
+// read from the right, skipping the proxies, so a client can't spoof it by
+// sending its own.
+func resolveClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(peer)
+	if err != nil || !trusted(addr, trustedProxies) {
+		return peer
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+		if !trusted(addr, trustedProxies) {
+			break
+		}
+	}
+	return addr.String()
+}
+
+func trusted(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanupRateLimits forgets buckets that haven't been used for an hour.
+func (cfg *apiConfig) cleanupRateLimits(ctx context.Context) {
+	if err := cfg.rateLimiter.Cleanup(ctx, time.Now().Add(-time.Hour)); err != nil {
+		slog.Error("Failed to clean up rate limits", "error", err)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
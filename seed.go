@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/marekbrze/chirpy/internal/config"
+	"github.com/marekbrze/chirpy/internal/seed"
+)
+
+const seedUsage = `Usage: chirpy seed [seed flags] [-- config flags]
+
+Fills an empty database with generated users, chirps with hashtags and
+mentions, blocks, mutes and refresh tokens. Chirpy has no follows or likes,
+so who follows whom only shows up as mentions. Refused on the production
+platform.
+
+`
+
+// runSeed handles the seed subcommand. Its own flags come first; anything
+// after -- is read like the server's flags, for the database URL and
+// platform.
+func runSeed(ctx context.Context, args []string) error {
+	opts := seed.Options{Now: time.Now().UTC()}
+	flags := flag.NewFlagSet("chirpy seed", flag.ContinueOnError)
+	flags.SetOutput(os.Stdout)
+	flags.Usage = func() {
+		fmt.Print(seedUsage)
+		flags.PrintDefaults()
+	}
+	flags.IntVar(&opts.Users, "users", 100, "number of users")
+	flags.IntVar(&opts.ChirpsPerUser, "chirps", 20, "average chirps per user")
+	flags.IntVar(&opts.FollowsPerUser, "follows", 10, "average users each user mentions")
+	flags.IntVar(&opts.BlocksPerUser, "blocks", 1, "average blocks per user")
+	flags.IntVar(&opts.MutesPerUser, "mutes", 2, "average mutes per user")
+	flags.IntVar(&opts.TokensPerUser, "tokens", 2, "refresh tokens per user")
+	flags.DurationVar(&opts.Period, "period", 90*24*time.Hour, "how far back accounts and chirps go")
+	flags.StringVar(&opts.Password, "password", "chirpy-seed", "password for every generated user")
+	flags.Uint64Var(&opts.Seed, "seed", 1, "random seed, for repeatable content")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	cfg, err := config.Load(flags.Args(), os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Print(seedUsage, config.Usage())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if cfg.DatabaseURL == "" {
+		return errors.New("database_url is required (set CHIRPY_URL or -database-url)")
+	}
+	if cfg.Platform == "production" {
+		return errors.New("refusing to seed on the production platform (set PLATFORM or -platform)")
+	}
+	db, migrator, err := openDatabase(cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := prepareSchema(ctx, migrator, cfg.AutoMigrate); err != nil {
+		return err
+	}
+	start := time.Now()
+	stats, err := seed.Run(ctx, newTransactor(cfg.DatabaseURL, db), opts, os.Stdout)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Seeded %d users and %d chirps in %s; every user's password is %q\n", stats.Users, stats.Chirps, time.Since(start).Round(time.Millisecond), opts.Password)
+	return nil
+}
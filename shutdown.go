@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/marekbrze/chirpy/internal/health"
+)
+
+// serve runs server until ctx is cancelled. It then fails health checks for
+// delay so the load balancer stops sending traffic, and gives in-flight
+// requests up to timeout to finish.
+func (cfg *apiConfig) serve(ctx context.Context, server *http.Server, delay, timeout time.Duration) error {
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down, draining connections", "delay", delay, "timeout", timeout)
+	cfg.draining.Store(true)
+	time.Sleep(delay)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serverErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// workers runs background jobs so shutdown can wait for them to return.
+type workers struct {
+	wg         sync.WaitGroup
+	heartbeats []*health.Heartbeat
+}
+
+// every runs job each interval until ctx is cancelled. The worker's heartbeat
+// check fails when a run is more than one interval late.
+func (w *workers) every(ctx context.Context, name string, interval time.Duration, job func(ctx context.Context)) {
+	heartbeat := health.NewHeartbeat(name, 2*interval)
+	w.heartbeats = append(w.heartbeats, heartbeat)
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				job(ctx)
+				heartbeat.Beat()
+			}
+		}
+	}()
+}
+
+func (w *workers) checks() []health.Check {
+	checks := []health.Check{}
+	for _, heartbeat := range w.heartbeats {
+		checks = append(checks, heartbeat.Check())
+	}
+	return checks
+}
+
+func (w *workers) wait() {
+	w.wg.Wait()
+}
@@ -0,0 +1,32 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (id, created_at, action, actor_id, target_id, ip, user_agent, metadata)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+);
+
+-- name: GetAuditEventsByTarget :many
+SELECT * FROM audit_events
+WHERE target_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: ListAuditEvents :many
+SELECT * FROM audit_events
+WHERE (action = sqlc.narg('action') OR sqlc.narg('action') IS NULL)
+AND (actor_id = sqlc.narg('actor_id') OR sqlc.narg('actor_id') IS NULL)
+AND (target_id = sqlc.narg('target_id') OR sqlc.narg('target_id') IS NULL)
+AND (created_at >= sqlc.narg('since') OR sqlc.narg('since') IS NULL)
+AND (created_at < sqlc.narg('until') OR sqlc.narg('until') IS NULL)
+ORDER BY created_at DESC, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetSecurityLog :many
+SELECT * FROM audit_events
+WHERE actor_id = sqlc.arg('user_id') OR target_id = sqlc.arg('user_id')
+ORDER BY created_at DESC, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: PseudonymizeAuditEvent :exec
+UPDATE audit_events
+SET ip = '', user_agent = '', metadata = $2
+WHERE id = $1;
@@ -0,0 +1,15 @@
+-- name: UpsertAvatar :exec
+INSERT INTO avatars (user_id, content_type, data, updated_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id) DO UPDATE
+SET content_type = excluded.content_type,
+data = excluded.data,
+updated_at = excluded.updated_at;
+
+-- name: GetAvatar :one
+SELECT * FROM avatars
+WHERE user_id = $1;
+
+-- name: DeleteAvatar :execrows
+DELETE FROM avatars
+WHERE user_id = $1;
@@ -0,0 +1,27 @@
+-- name: CreateBlock :exec
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteBlock :exec
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: CreateMute :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteMute :exec
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetBlocksByBlocker :many
+SELECT * FROM blocks
+WHERE blocker_id = $1
+ORDER BY created_at;
+
+-- name: GetMutesByMuter :many
+SELECT * FROM mutes
+WHERE muter_id = $1
+ORDER BY created_at;
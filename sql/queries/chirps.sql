@@ -5,14 +5,67 @@ VALUES (
 )
 RETURNING *;
 
--- name: GetAllChirps :many 
-SELECT * FROM chirps
-WHERE (user_id = sqlc.narg('user_id') OR sqlc.narg('user_id') IS NULL)
-ORDER BY created_at;
+-- name: GetAllChirps :many
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN mutes
+    ON mutes.muted_id = chirps.user_id
+    AND mutes.muter_id = sqlc.narg('viewer_id')
+LEFT JOIN blocks AS blocked_by_viewer
+    ON blocked_by_viewer.blocked_id = chirps.user_id
+    AND blocked_by_viewer.blocker_id = sqlc.narg('viewer_id')
+LEFT JOIN blocks AS blocking_viewer
+    ON blocking_viewer.blocker_id = chirps.user_id
+    AND blocking_viewer.blocked_id = sqlc.narg('viewer_id')
+WHERE (chirps.user_id = sqlc.narg('user_id') OR sqlc.narg('user_id') IS NULL)
+AND chirps.hidden_at IS NULL
+AND chirps.deleted_at IS NULL
+AND users.deleted_at IS NULL
+AND mutes.muter_id IS NULL
+AND blocked_by_viewer.blocker_id IS NULL
+AND blocking_viewer.blocker_id IS NULL
+ORDER BY chirps.created_at;
 
 -- name: GetChirp :one
+-- Chirps by accounts pending deletion are gone as far as readers can tell.
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = $1 AND chirps.deleted_at IS NULL AND users.deleted_at IS NULL;
+
+-- name: GetChirpIncludingDeleted :one
 SELECT * FROM chirps
 WHERE id = $1;
 
 -- name: DeleteChirp :exec
-DELETE FROM chirps WHERE id = $1;
+UPDATE chirps
+SET deleted_at = $1, updated_at = $2
+WHERE id = $3 AND deleted_at IS NULL;
+
+-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL, updated_at = $1
+WHERE id = $2 AND user_id = $3 AND deleted_at > $4
+RETURNING *;
+
+-- name: PurgeDeletedChirps :execrows
+DELETE FROM chirps
+WHERE deleted_at < $1;
+
+-- name: SetChirpHidden :one
+UPDATE chirps
+SET hidden_at = $1, updated_at = $2
+WHERE id = $3
+RETURNING *;
+
+-- name: GetChirpCountsByDay :many
+-- Counts every chirp created, including ones since hidden or deleted.
+SELECT date_trunc('day', created_at)::TIMESTAMP AS day, COUNT(*) AS count
+FROM chirps
+WHERE created_at >= sqlc.arg('since')
+GROUP BY day
+ORDER BY day;
+
+-- name: GetUserChirps :many
+SELECT * FROM chirps
+WHERE user_id = $1
+ORDER BY created_at;
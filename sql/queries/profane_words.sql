@@ -0,0 +1,3 @@
+-- name: GetProfaneWords :many
+SELECT word, action FROM profane_words
+ORDER BY word;
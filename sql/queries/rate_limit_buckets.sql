@@ -0,0 +1,33 @@
+-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at)
+VALUES (
+    sqlc.arg('key'),
+    sqlc.arg('burst')::float8 - 1,
+    true,
+    sqlc.arg('now')::timestamp
+)
+ON CONFLICT (key) DO UPDATE
+SET (tokens, allowed, updated_at) = (
+    SELECT
+        CASE
+            WHEN refilled.tokens >= 1 THEN refilled.tokens - 1
+            ELSE refilled.tokens
+        END,
+        refilled.tokens >= 1,
+        excluded.updated_at
+    FROM (
+        SELECT LEAST(
+            sqlc.arg('burst')::float8,
+            rate_limit_buckets.tokens
+            + GREATEST(0, EXTRACT(
+                EPOCH FROM (excluded.updated_at - rate_limit_buckets.updated_at)
+            )::float8)
+            * sqlc.arg('rate')::float8
+        ) AS tokens
+    ) AS refilled
+)
+RETURNING tokens, allowed;
+
+-- name: DeleteStaleRateLimitBuckets :exec
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1;
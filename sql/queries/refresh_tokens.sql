@@ -4,7 +4,7 @@ VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: GetTokenInfo :one
-SELECT t1.*, t2.id, t2.email
+SELECT t1.*, t2.id, t2.email, t2.suspended_at
 FROM refresh_tokens AS t1
 INNER JOIN users AS t2 ON t1.user_id = t2.id
 WHERE t1.token = $1;
@@ -13,3 +13,13 @@ WHERE t1.token = $1;
 UPDATE refresh_tokens
 SET revoked_at = $1, updated_at = $2
 WHERE token = $3;
+
+-- name: RevokeUserTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = $1, updated_at = $2
+WHERE user_id = $3 AND revoked_at IS NULL;
+
+-- name: GetUserRefreshTokens :many
+SELECT * FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at;
@@ -0,0 +1,39 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, chirp_id, reporter_id, reason, details)
+VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+RETURNING *;
+
+-- name: GetReport :one
+SELECT * FROM reports
+WHERE id = $1;
+
+-- name: GetOpenReports :many
+SELECT * FROM reports
+WHERE status = 'open'
+ORDER BY created_at;
+
+-- name: GetOpenReportsWithChirps :many
+-- The chirp is included even when it has been hidden or deleted.
+SELECT sqlc.embed(reports), chirps.body AS chirp_body FROM reports
+JOIN chirps ON chirps.id = reports.chirp_id
+WHERE reports.status = 'open'
+ORDER BY reports.created_at;
+
+-- name: ResolveReport :one
+-- Only open reports can be resolved, so two admins can't both act on one.
+UPDATE reports
+SET status = $1, resolved_at = $2, resolved_by = $3, updated_at = $4
+WHERE id = $5 AND status = 'open'
+RETURNING *;
+
+-- name: ResolveChirpReports :exec
+UPDATE reports
+SET status = $1, resolved_at = $2, resolved_by = $3, updated_at = $4
+WHERE chirp_id = $5 AND status = 'open';
+
+-- name: GetReportsByReporter :many
+SELECT * FROM reports
+WHERE reporter_id = $1
+ORDER BY created_at;
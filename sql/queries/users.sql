@@ -14,8 +14,13 @@ SELECT * FROM users
 WHERE email = $1;
 
 -- name: UpdateUser :one
+-- A forced password reset is done once the password changes.
 UPDATE users
-SET email = $1, hashed_password = $2, updated_at = $3
+SET
+    email = $1,
+    hashed_password = $2,
+    password_reset_required = password_reset_required AND hashed_password = $2,
+    updated_at = $3
 WHERE id = $4
 RETURNING *;
 
@@ -24,3 +29,83 @@ UPDATE users
 SET is_chirpy_red = $1, updated_at = $2
 WHERE id = $3
 RETURNING *;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: SetUserSuspended :one
+UPDATE users
+SET suspended_at = $1, updated_at = $2
+WHERE id = $3
+RETURNING *;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET handle = $1, display_name = $2, bio = $3, updated_at = $4
+WHERE id = $5
+RETURNING *;
+
+-- name: GetUserProfile :one
+SELECT
+    users.*,
+    (
+        SELECT COUNT(*) FROM chirps
+        WHERE chirps.user_id = users.id
+        AND chirps.hidden_at IS NULL
+        AND chirps.deleted_at IS NULL
+    ) AS chirp_count,
+    EXISTS (
+        SELECT 1 FROM avatars WHERE avatars.user_id = users.id
+    ) AS has_avatar
+FROM users
+WHERE users.handle = $1 AND users.deleted_at IS NULL;
+
+-- name: GetAuthors :many
+SELECT
+    users.id,
+    users.handle,
+    users.display_name,
+    EXISTS (
+        SELECT 1 FROM avatars WHERE avatars.user_id = users.id
+    ) AS has_avatar
+FROM users
+WHERE users.id = ANY(sqlc.arg('ids')::UUID [])
+ORDER BY users.id;
+
+-- name: SetUserAdmin :one
+UPDATE users
+SET is_admin = $1, updated_at = $2
+WHERE id = $3
+RETURNING *;
+
+-- name: SetUserDeleted :one
+UPDATE users
+SET deleted_at = $1, updated_at = $2
+WHERE id = $3
+RETURNING *;
+
+-- name: GetPurgeableUserIDs :many
+SELECT id FROM users
+WHERE deleted_at < $1;
+
+-- name: PurgeDeletedUsers :execrows
+DELETE FROM users
+WHERE deleted_at < $1;
+
+-- name: GetRecentUsers :many
+SELECT * FROM users
+ORDER BY created_at DESC, id
+LIMIT $1;
+
+-- name: SearchUsers :many
+SELECT * FROM users
+WHERE LOWER(email) LIKE '%' || LOWER(sqlc.arg('query')) || '%' ESCAPE '\'
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: SetPasswordResetRequired :one
+UPDATE users
+SET password_reset_required = $1, updated_at = $2
+WHERE id = $3
+RETURNING *;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE profane_words (
+    word TEXT PRIMARY KEY,
+    action TEXT NOT NULL DEFAULT 'mask',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_profane_words_action CHECK (
+        action IN ('mask', 'reject', 'flag')
+    )
+);
+
+-- +goose Down
+DROP TABLE profane_words;
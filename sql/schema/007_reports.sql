@@ -0,0 +1,46 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN is_admin bool NOT NULL DEFAULT false,
+ADD COLUMN suspended_at TIMESTAMP NULL DEFAULT NULL;
+
+ALTER TABLE chirps
+ADD COLUMN hidden_at TIMESTAMP NULL DEFAULT NULL;
+
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL,
+    reporter_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    resolved_at TIMESTAMP NULL DEFAULT NULL,
+    resolved_by UUID NULL DEFAULT NULL,
+    CONSTRAINT fk_reports_chirps FOREIGN KEY (chirp_id) REFERENCES chirps (
+        id
+    ) ON DELETE CASCADE,
+    CONSTRAINT fk_reports_users FOREIGN KEY (reporter_id) REFERENCES users (
+        id
+    ) ON DELETE CASCADE,
+    CONSTRAINT fk_reports_resolvers FOREIGN KEY (resolved_by) REFERENCES users (
+        id
+    ) ON DELETE SET NULL,
+    CONSTRAINT uq_reports_chirp_reporter UNIQUE (chirp_id, reporter_id),
+    CONSTRAINT chk_reports_reason CHECK (
+        reason IN ('spam', 'harassment', 'hate', 'violence', 'misinformation', 'other')
+    ),
+    CONSTRAINT chk_reports_status CHECK (
+        status IN ('open', 'dismissed', 'actioned')
+    )
+);
+
+-- +goose Down
+DROP TABLE reports;
+
+ALTER TABLE chirps
+DROP COLUMN hidden_at;
+
+ALTER TABLE users
+DROP COLUMN suspended_at,
+DROP COLUMN is_admin;
@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE blocks (
+    blocker_id UUID NOT NULL,
+    blocked_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (blocker_id, blocked_id),
+    CONSTRAINT fk_blocks_blockers FOREIGN KEY (blocker_id) REFERENCES users (
+        id
+    ) ON DELETE CASCADE,
+    CONSTRAINT fk_blocks_blocked FOREIGN KEY (blocked_id) REFERENCES users (
+        id
+    ) ON DELETE CASCADE
+);
+
+CREATE TABLE mutes (
+    muter_id UUID NOT NULL,
+    muted_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (muter_id, muted_id),
+    CONSTRAINT fk_mutes_muters FOREIGN KEY (muter_id) REFERENCES users (
+        id
+    ) ON DELETE CASCADE,
+    CONSTRAINT fk_mutes_muted FOREIGN KEY (muted_id) REFERENCES users (
+        id
+    ) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE mutes;
+DROP TABLE blocks;
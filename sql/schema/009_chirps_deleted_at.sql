@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN deleted_at TIMESTAMP NULL DEFAULT NULL;
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN deleted_at;
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN handle TEXT NULL DEFAULT NULL UNIQUE;
+ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN bio TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE avatars (
+    user_id UUID PRIMARY KEY,
+    content_type TEXT NOT NULL,
+    data BYTEA NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_avatars_users FOREIGN KEY (user_id) REFERENCES users (
+        id
+    ) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE avatars;
+ALTER TABLE users DROP COLUMN bio;
+ALTER TABLE users DROP COLUMN display_name;
+ALTER TABLE users DROP COLUMN handle;
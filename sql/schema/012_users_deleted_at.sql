@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP NULL DEFAULT NULL;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN deleted_at;
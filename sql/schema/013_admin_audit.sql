@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN password_reset_required BOOL NOT NULL DEFAULT false;
+
+-- Audit events have no foreign keys, so the record of what happened outlives
+-- the users involved.
+CREATE TABLE audit_events (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    action TEXT NOT NULL,
+    actor_id UUID NULL DEFAULT NULL,
+    target_id UUID NULL DEFAULT NULL,
+    ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    metadata JSONB NOT NULL DEFAULT '{}'
+);
+CREATE INDEX audit_events_target_id_created_at_idx ON audit_events (target_id, created_at);
+
+-- +goose Down
+DROP TABLE audit_events;
+ALTER TABLE users DROP COLUMN password_reset_required;
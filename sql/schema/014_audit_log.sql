@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_events_append_only
+BEFORE UPDATE OR DELETE ON audit_events
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE INDEX audit_events_actor_id_created_at_idx ON audit_events (actor_id, created_at);
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+
+-- +goose Down
+DROP INDEX audit_events_created_at_idx;
+DROP INDEX audit_events_actor_id_created_at_idx;
+DROP TRIGGER audit_events_append_only ON audit_events;
+DROP FUNCTION audit_events_append_only;
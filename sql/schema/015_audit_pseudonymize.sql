@@ -0,0 +1,34 @@
+-- +goose Up
+-- Purging an account pseudonymizes its audit events: the IP address and user
+-- agent are cleared and emails in the metadata are swapped for their hashes.
+-- That's the only update allowed; everything else about an event stays put.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND NEW.id = OLD.id
+        AND NEW.created_at = OLD.created_at
+        AND NEW.action = OLD.action
+        AND NEW.actor_id IS NOT DISTINCT FROM OLD.actor_id
+        AND NEW.target_id IS NOT DISTINCT FROM OLD.target_id
+        AND NEW.ip = ''
+        AND NEW.user_agent = ''
+        AND NOT NEW.metadata ?| ARRAY['email', 'previous_email']
+        AND NEW.metadata - 'email_sha256' - 'previous_email_sha256'
+            = OLD.metadata - 'email' - 'previous_email' - 'email_sha256' - 'previous_email_sha256'
+    THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
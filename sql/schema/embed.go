@@ -0,0 +1,7 @@
+// Package schema is used for embedding the goose migrations into the binary
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
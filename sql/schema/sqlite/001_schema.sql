@@ -0,0 +1,117 @@
+-- +goose Up
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    email TEXT NOT NULL UNIQUE,
+    hashed_password TEXT NOT NULL DEFAULT 'unset',
+    is_chirpy_red BOOLEAN NOT NULL DEFAULT false,
+    is_admin BOOLEAN NOT NULL DEFAULT false,
+    suspended_at TIMESTAMP NULL DEFAULT NULL
+);
+
+CREATE TABLE chirps (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    body TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    hidden_at TIMESTAMP NULL DEFAULT NULL,
+    deleted_at TIMESTAMP NULL DEFAULT NULL,
+    CONSTRAINT fk_feeds_users FOREIGN KEY (user_id) REFERENCES users (
+        id
+    ) ON DELETE CASCADE
+);
+
+CREATE TABLE refresh_tokens (
+    token TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NULL DEFAULT NULL,
+    user_id TEXT NOT NULL,
+    CONSTRAINT fk_feeds_users FOREIGN KEY (user_id) REFERENCES users (
+        id
+    ) ON DELETE CASCADE
+);
+
+CREATE TABLE profane_words (
+    word TEXT PRIMARY KEY,
+    action TEXT NOT NULL DEFAULT 'mask',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_profane_words_action CHECK (
+        action IN ('mask', 'reject', 'flag')
+    )
+);
+
+CREATE TABLE reports (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    chirp_id TEXT NOT NULL,
+    reporter_id TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    resolved_at TIMESTAMP NULL DEFAULT NULL,
+    resolved_by TEXT NULL DEFAULT NULL,
+    CONSTRAINT fk_reports_chirps FOREIGN KEY (chirp_id) REFERENCES chirps (
+        id
+    ) ON DELETE CASCADE,
+    CONSTRAINT fk_reports_users FOREIGN KEY (reporter_id) REFERENCES users (
+        id
+    ) ON DELETE CASCADE,
+    CONSTRAINT fk_reports_resolvers FOREIGN KEY (resolved_by) REFERENCES users (
+        id
+    ) ON DELETE SET NULL,
+    CONSTRAINT uq_reports_chirp_reporter UNIQUE (chirp_id, reporter_id),
+    CONSTRAINT chk_reports_reason CHECK (
+        reason IN ('spam', 'harassment', 'hate', 'violence', 'misinformation', 'other')
+    ),
+    CONSTRAINT chk_reports_status CHECK (
+        status IN ('open', 'dismissed', 'actioned')
+    )
+);
+
+CREATE TABLE blocks (
+    blocker_id TEXT NOT NULL,
+    blocked_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (blocker_id, blocked_id),
+    CONSTRAINT fk_blocks_blockers FOREIGN KEY (blocker_id) REFERENCES users (
+        id
+    ) ON DELETE CASCADE,
+    CONSTRAINT fk_blocks_blocked FOREIGN KEY (blocked_id) REFERENCES users (
+        id
+    ) ON DELETE CASCADE
+);
+
+CREATE TABLE mutes (
+    muter_id TEXT NOT NULL,
+    muted_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (muter_id, muted_id),
+    CONSTRAINT fk_mutes_muters FOREIGN KEY (muter_id) REFERENCES users (
+        id
+    ) ON DELETE CASCADE,
+    CONSTRAINT fk_mutes_muted FOREIGN KEY (muted_id) REFERENCES users (
+        id
+    ) ON DELETE CASCADE
+);
+
+CREATE TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens REAL NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE rate_limit_buckets;
+DROP TABLE mutes;
+DROP TABLE blocks;
+DROP TABLE reports;
+DROP TABLE profane_words;
+DROP TABLE refresh_tokens;
+DROP TABLE chirps;
+DROP TABLE users;
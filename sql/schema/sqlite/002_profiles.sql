@@ -0,0 +1,23 @@
+-- +goose Up
+-- SQLite can't add a UNIQUE column, so the index enforces it instead.
+ALTER TABLE users ADD COLUMN handle TEXT NULL DEFAULT NULL;
+ALTER TABLE users ADD COLUMN display_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN bio TEXT NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX users_handle_key ON users (handle);
+
+CREATE TABLE avatars (
+    user_id TEXT PRIMARY KEY,
+    content_type TEXT NOT NULL,
+    data BLOB NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_avatars_users FOREIGN KEY (user_id) REFERENCES users (
+        id
+    ) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE avatars;
+DROP INDEX users_handle_key;
+ALTER TABLE users DROP COLUMN bio;
+ALTER TABLE users DROP COLUMN display_name;
+ALTER TABLE users DROP COLUMN handle;
@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE audit_events (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    action TEXT NOT NULL,
+    actor_id TEXT NULL DEFAULT NULL,
+    target_id TEXT NULL DEFAULT NULL,
+    ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    metadata TEXT NOT NULL DEFAULT '{}'
+);
+CREATE INDEX audit_events_target_id_created_at_idx ON audit_events (target_id, created_at);
+
+-- +goose Down
+DROP TABLE audit_events;
+ALTER TABLE users DROP COLUMN password_reset_required;
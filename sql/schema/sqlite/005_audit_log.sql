@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+-- +goose StatementEnd
+
+CREATE INDEX audit_events_actor_id_created_at_idx ON audit_events (actor_id, created_at);
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+
+-- +goose Down
+DROP INDEX audit_events_created_at_idx;
+DROP INDEX audit_events_actor_id_created_at_idx;
+DROP TRIGGER audit_events_no_delete;
+DROP TRIGGER audit_events_no_update;
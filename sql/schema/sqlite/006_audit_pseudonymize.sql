@@ -0,0 +1,35 @@
+-- +goose Up
+-- Purging an account pseudonymizes its audit events: the IP address and user
+-- agent are cleared and emails in the metadata are swapped for their hashes.
+-- That's the only update allowed; everything else about an event stays put.
+DROP TRIGGER audit_events_no_update;
+
+-- +goose StatementBegin
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+WHEN NOT (
+    NEW.id = OLD.id
+    AND NEW.created_at = OLD.created_at
+    AND NEW.action = OLD.action
+    AND NEW.actor_id IS OLD.actor_id
+    AND NEW.target_id IS OLD.target_id
+    AND NEW.ip = ''
+    AND NEW.user_agent = ''
+    AND json_type(CAST(NEW.metadata AS TEXT), '$.email') IS NULL
+    AND json_type(CAST(NEW.metadata AS TEXT), '$.previous_email') IS NULL
+    AND json_remove(CAST(NEW.metadata AS TEXT), '$.email_sha256', '$.previous_email_sha256')
+        = json_remove(CAST(OLD.metadata AS TEXT), '$.email', '$.previous_email', '$.email_sha256', '$.previous_email_sha256')
+)
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER audit_events_no_update;
+
+-- +goose StatementBegin
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+-- +goose StatementEnd
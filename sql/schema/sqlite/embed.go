@@ -0,0 +1,9 @@
+// Package sqlite is used for embedding the SQLite goose migrations into the
+// binary. They start from the Postgres schema at version 10 and must get a
+// matching migration whenever sql/schema does.
+package sqlite
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
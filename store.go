@@ -0,0 +1,59 @@
+package main
+
+import (
+	"database/sql"
+
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/migrate"
+	"github.com/marekbrze/chirpy/internal/sqlitedb"
+	"github.com/marekbrze/chirpy/internal/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// openDatabase connects to SQLite when the URL starts with sqlite: and to
+// Postgres otherwise, and returns the migrations written for that database.
+func openDatabase(databaseURL string) (*sql.DB, *migrate.Migrator, error) {
+	if sqlitedb.IsURL(databaseURL) {
+		db, err := sqlitedb.Open(databaseURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		migrator, err := migrate.NewSQLite(db)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return db, migrator, nil
+	}
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	migrator, err := migrate.New(db)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, migrator, nil
+}
+
+// newQueries takes the pool or a transaction.
+func newQueries(databaseURL string, db database.DBTX) database.Querier {
+	if sqlitedb.IsURL(databaseURL) {
+		return sqlitedb.New(tracing.WrapDB(db, semconv.DBSystemSqlite))
+	}
+	return database.New(tracing.WrapDB(db, semconv.DBSystemPostgreSQL))
+}
+
+// newTransactor runs Postgres transactions as serializable, so units of work
+// that read and then write can't interleave; conflicts are retried. SQLite
+// transactions are always serializable and the driver takes no options.
+func newTransactor(databaseURL string, db *sql.DB) database.Transactor {
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable}
+	if sqlitedb.IsURL(databaseURL) {
+		opts = nil
+	}
+	return database.NewTransactor(db, opts, func(tx database.DBTX) database.Querier {
+		return newQueries(databaseURL, tx)
+	})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// middlewareTracing starts a span for every request, continuing the trace
+// from an incoming traceparent header.
+func middlewareTracing(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "http.request")
+}
+
+// middlewareRouteSpanName names the request span after the route pattern.
+// It has to wrap the mux directly because the mux only sets the pattern on
+// the request it was given.
+func middlewareRouteSpanName(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if r.Pattern == "" {
+			return
+		}
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Pattern)
+		_, route, found := strings.Cut(r.Pattern, " ")
+		if !found {
+			route = r.Pattern
+		}
+		span.SetAttributes(semconv.HTTPRoute(route))
+	})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/problem"
+)
+
+func (cfg *apiConfig) blockUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationshipUsers(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.CreateBlock(r.Context(), database.CreateBlockParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+		CreatedAt: time.Now().UTC(),
+	})
+	respondToRelationshipChange(w, r, err)
+}
+
+func (cfg *apiConfig) unblockUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationshipUsers(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.DeleteBlock(r.Context(), database.DeleteBlockParams{
+		BlockerID: userID,
+		BlockedID: targetID,
+	})
+	respondToRelationshipChange(w, r, err)
+}
+
+func (cfg *apiConfig) muteUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationshipUsers(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.CreateMute(r.Context(), database.CreateMuteParams{
+		MuterID:   userID,
+		MutedID:   targetID,
+		CreatedAt: time.Now().UTC(),
+	})
+	respondToRelationshipChange(w, r, err)
+}
+
+func (cfg *apiConfig) unmuteUser(w http.ResponseWriter, r *http.Request) {
+	userID, targetID, ok := cfg.relationshipUsers(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.DeleteMute(r.Context(), database.DeleteMuteParams{
+		MuterID: userID,
+		MutedID: targetID,
+	})
+	respondToRelationshipChange(w, r, err)
+}
+
+// relationshipUsers returns the authenticated user and the user from the
+// path, writing the error response itself when ok is false.
+func (cfg *apiConfig) relationshipUsers(w http.ResponseWriter, r *http.Request) (userID, targetID uuid.UUID, ok bool) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	targetID, err = parseUUIDPathValue(r, "userID")
+	if err != nil {
+		respondWithError(w, r, err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	if userID == targetID {
+		respondWithError(w, r, problem.Invalid(problem.FieldError{Field: "userID", Message: "can't be yourself"}))
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, targetID, true
+}
+
+func respondToRelationshipChange(w http.ResponseWriter, r *http.Request, err error) {
+	if err != nil {
+		if database.IsForeignKeyViolation(err) {
+			respondWithError(w, r, errUserNotFound)
+			return
+		}
+		respondWithError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
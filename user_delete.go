@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/marekbrze/chirpy/internal/auth"
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/validate"
+)
+
+type userDeleteData struct {
+	Password string `json:"password"`
+}
+
+func (d userDeleteData) validate(v *validate.Validator) {
+	v.Check(validate.NotBlank(d.Password), "password", "is required to delete the account")
+}
+
+type pendingDeletion struct {
+	DeletedAt  time.Time `json:"deleted_at"`
+	PurgeAfter time.Time `json:"purge_after"`
+}
+
+// deleteUser schedules the account for deletion. Nothing is removed straight
+// away: every refresh token is revoked, and logging in again before the grace
+// period ends cancels the deletion. purgeDeletedUsers removes the rest.
+func (cfg *apiConfig) deleteUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	receivedData := userDeleteData{}
+	err = decodeJSON(w, r, &receivedData)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	deletedAt := time.Now().UTC()
+	err = cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		user, err := q.GetUserByID(r.Context(), userID)
+		if err == sql.ErrNoRows {
+			return errUnauthorized
+		}
+		if err != nil {
+			return err
+		}
+		passwordCorrect, err := auth.CheckPasswordHash(receivedData.Password, user.HashedPassword)
+		if err != nil || !passwordCorrect {
+			return errIncorrectPassword
+		}
+		if user.DeletedAt.Valid {
+			deletedAt = user.DeletedAt.Time
+		}
+		_, err = q.SetUserDeleted(r.Context(), database.SetUserDeletedParams{
+			DeletedAt: sql.NullTime{Time: deletedAt, Valid: true},
+			UpdatedAt: time.Now().UTC(),
+			ID:        user.ID,
+		})
+		if err != nil {
+			return err
+		}
+		err = q.RevokeUserTokens(r.Context(), database.RevokeUserTokensParams{
+			RevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			UpdatedAt: time.Now().UTC(),
+			UserID:    user.ID,
+		})
+		if err != nil {
+			return err
+		}
+		return audit(r.Context(), q, r, auditDeletionRequest, user.ID, user.ID, nil)
+	})
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 202, pendingDeletion{
+		DeletedAt:  deletedAt,
+		PurgeAfter: deletedAt.Add(cfg.accountDeletionGracePeriod),
+	})
+}
+
+// purgeDeletedUsers hard-deletes accounts whose grace period has run out.
+// Their chirps, tokens, reports, blocks, mutes and avatar go with them through
+// the foreign key cascades. Audit events outlive them, pseudonymized.
+func (cfg *apiConfig) purgeDeletedUsers(ctx context.Context) {
+	cutoff := sql.NullTime{
+		Time:  time.Now().UTC().Add(-cfg.accountDeletionGracePeriod),
+		Valid: true,
+	}
+	var purged int64
+	err := cfg.tx.InTx(ctx, func(q database.Querier) error {
+		userIDs, err := q.GetPurgeableUserIDs(ctx, cutoff)
+		if err != nil {
+			return err
+		}
+		for _, userID := range userIDs {
+			if err := pseudonymizeAuditEvents(ctx, q, userID); err != nil {
+				return err
+			}
+		}
+		purged, err = q.PurgeDeletedUsers(ctx, cutoff)
+		return err
+	})
+	if err != nil {
+		slog.Error("Failed to purge deleted users", "error", err)
+		return
+	}
+	if purged > 0 {
+		slog.Info("Purged deleted users", "count", purged)
+	}
+}
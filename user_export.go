@@ -0,0 +1,216 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+)
+
+// The export has everything we store about a user except the password hash
+// and the refresh token values, which would let whoever holds the archive log
+// in.
+type exportedChirp struct {
+	chirp
+	HiddenAt  *time.Time `json:"hidden_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type exportedRefreshToken struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type exportedRelation struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type userExport struct {
+	user          userDetails
+	chirps        []exportedChirp
+	refreshTokens []exportedRefreshToken
+	reports       []report
+	blocks        []exportedRelation
+	mutes         []exportedRelation
+	securityLog   []auditEvent
+	avatar        *database.Avatar
+}
+
+// exportUser sends a ZIP archive with one JSON file per kind of data, plus
+// the avatar if there is one. It's built in memory so a failure halfway
+// through is still reported as a problem instead of a truncated download.
+func (cfg *apiConfig) exportUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	var export userExport
+	err = cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		var err error
+		export, err = loadUserExport(r.Context(), q, userID)
+		if err != nil {
+			return err
+		}
+		return audit(r.Context(), q, r, auditDataExport, userID, userID, nil)
+	})
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	archive, err := export.zip()
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	filename := "chirpy-export-" + time.Now().UTC().Format("2006-01-02") + ".zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(200)
+	w.Write(archive)
+}
+
+// exportPageSize is how many audit events are read at a time. The security
+// log is the only part of the export without a natural bound.
+const exportPageSize = 500
+
+func loadUserExport(ctx context.Context, q database.Querier, userID uuid.UUID) (userExport, error) {
+	user, err := q.GetUserByID(ctx, userID)
+	if err == sql.ErrNoRows {
+		return userExport{}, errUnauthorized
+	}
+	if err != nil {
+		return userExport{}, err
+	}
+	export := userExport{
+		user:          userDetailsFromDB(user),
+		chirps:        []exportedChirp{},
+		refreshTokens: []exportedRefreshToken{},
+		reports:       []report{},
+		blocks:        []exportedRelation{},
+		mutes:         []exportedRelation{},
+		securityLog:   []auditEvent{},
+	}
+
+	dbChirps, err := q.GetUserChirps(ctx, userID)
+	if err != nil {
+		return userExport{}, err
+	}
+	for _, dbChirp := range dbChirps {
+		export.chirps = append(export.chirps, exportedChirp{
+			chirp:     chirpFromDB(dbChirp),
+			HiddenAt:  nullTime(dbChirp.HiddenAt),
+			DeletedAt: nullTime(dbChirp.DeletedAt),
+		})
+	}
+	dbTokens, err := q.GetUserRefreshTokens(ctx, userID)
+	if err != nil {
+		return userExport{}, err
+	}
+	for _, dbToken := range dbTokens {
+		export.refreshTokens = append(export.refreshTokens, exportedRefreshToken{
+			CreatedAt: dbToken.CreatedAt,
+			ExpiresAt: dbToken.ExpiresAt,
+			RevokedAt: nullTime(dbToken.RevokedAt),
+		})
+	}
+	dbReports, err := q.GetReportsByReporter(ctx, userID)
+	if err != nil {
+		return userExport{}, err
+	}
+	for _, dbReport := range dbReports {
+		export.reports = append(export.reports, reportFromDB(dbReport))
+	}
+	dbBlocks, err := q.GetBlocksByBlocker(ctx, userID)
+	if err != nil {
+		return userExport{}, err
+	}
+	for _, dbBlock := range dbBlocks {
+		export.blocks = append(export.blocks, exportedRelation{UserID: dbBlock.BlockedID, CreatedAt: dbBlock.CreatedAt})
+	}
+	dbMutes, err := q.GetMutesByMuter(ctx, userID)
+	if err != nil {
+		return userExport{}, err
+	}
+	for _, dbMute := range dbMutes {
+		export.mutes = append(export.mutes, exportedRelation{UserID: dbMute.MutedID, CreatedAt: dbMute.CreatedAt})
+	}
+	for offset := 0; ; offset += exportPageSize {
+		dbEvents, err := q.GetSecurityLog(ctx, database.GetSecurityLogParams{
+			UserID: uuid.NullUUID{UUID: userID, Valid: true},
+			Limit:  exportPageSize,
+			Offset: int32(offset),
+		})
+		if err != nil {
+			return userExport{}, err
+		}
+		for _, dbEvent := range dbEvents {
+			export.securityLog = append(export.securityLog, auditEventFromDB(dbEvent).forUser(userID))
+		}
+		if len(dbEvents) < exportPageSize {
+			break
+		}
+	}
+	avatar, err := q.GetAvatar(ctx, userID)
+	if err == nil {
+		export.avatar = &avatar
+	} else if err != sql.ErrNoRows {
+		return userExport{}, err
+	}
+	return export, nil
+}
+
+func (e userExport) zip() ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	files := []struct {
+		name    string
+		content any
+	}{
+		{"user.json", e.user},
+		{"chirps.json", e.chirps},
+		{"refresh_tokens.json", e.refreshTokens},
+		{"reports.json", e.reports},
+		{"blocks.json", e.blocks},
+		{"mutes.json", e.mutes},
+		{"security_log.json", e.securityLog},
+	}
+	for _, file := range files {
+		content, err := json.MarshalIndent(file.content, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := writeZipFile(archive, file.name, content); err != nil {
+			return nil, err
+		}
+	}
+	if e.avatar != nil {
+		name := "avatar." + strings.TrimPrefix(e.avatar.ContentType, "image/")
+		if err := writeZipFile(archive, name, e.avatar.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeZipFile(archive *zip.Writer, name string, content []byte) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	return err
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/problem"
+)
+
+// Handles are stored in lower case, so @Heisenberg and @heisenberg are the
+// same user.
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
+const maxAvatarBytes = 1 << 20
+
+var avatarContentTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+type profile struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url,omitempty"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ChirpCount  int64     `json:"chirp_count"`
+}
+
+// author is the part of a profile embedded in every chirp.
+type author struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      string    `json:"handle,omitempty"`
+	DisplayName string    `json:"display_name"`
+	AvatarURL   string    `json:"avatar_url,omitempty"`
+}
+
+func (cfg *apiConfig) getUserProfile(w http.ResponseWriter, r *http.Request) {
+	handle := sql.NullString{String: strings.ToLower(r.PathValue("handle")), Valid: true}
+	dbProfile, err := cfg.dbQueries.GetUserProfile(r.Context(), handle)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, r, errUserNotFound)
+			return
+		}
+		respondWithError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, profile{
+		ID:          dbProfile.ID,
+		CreatedAt:   dbProfile.CreatedAt,
+		Handle:      dbProfile.Handle.String,
+		DisplayName: dbProfile.DisplayName,
+		Bio:         dbProfile.Bio,
+		AvatarURL:   avatarURL(dbProfile.ID, dbProfile.HasAvatar),
+		IsChirpyRed: dbProfile.IsChirpyRed,
+		ChirpCount:  dbProfile.ChirpCount,
+	})
+}
+
+// uploadAvatar takes the image as the raw request body. The type is sniffed
+// from the content rather than trusted from the Content-Type header.
+func (cfg *apiConfig) uploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAvatarBytes))
+	if err != nil {
+		var tooLargeErr *http.MaxBytesError
+		if errors.As(err, &tooLargeErr) {
+			respondWithError(w, r, problem.Wrap(problem.ContentTooLarge, err, "Avatars must be at most 1 MiB"))
+			return
+		}
+		respondWithError(w, r, err)
+		return
+	}
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(avatarContentTypes, contentType) {
+		respondWithError(w, r, problem.New(problem.UnsupportedMediaType, "Avatars must be PNG, JPEG, GIF or WebP images"))
+		return
+	}
+	err = cfg.dbQueries.UpsertAvatar(r.Context(), database.UpsertAvatarParams{
+		UserID:      userID,
+		ContentType: contentType,
+		Data:        data,
+		UpdatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		if database.IsForeignKeyViolation(err) {
+			respondWithError(w, r, errUnauthorized)
+			return
+		}
+		respondWithError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", avatarURL(userID, true))
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) deleteAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	if _, err := cfg.dbQueries.DeleteAvatar(r.Context(), userID); err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) getAvatar(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseUUIDPathValue(r, "userID")
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	avatar, err := cfg.dbQueries.GetAvatar(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, r, problem.New(problem.NotFound, "User has no avatar"))
+			return
+		}
+		respondWithError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", avatar.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	http.ServeContent(w, r, "", avatar.UpdatedAt, bytes.NewReader(avatar.Data))
+}
+
+func avatarURL(userID uuid.UUID, hasAvatar bool) string {
+	if !hasAvatar {
+		return ""
+	}
+	return "/api/users/" + userID.String() + "/avatar"
+}
+
+// addAuthors embeds the author of every chirp, loading them all with one
+// query so clients don't have to fetch them one by one.
+func (cfg *apiConfig) addAuthors(ctx context.Context, chirps []chirp) error {
+	seen := map[uuid.UUID]bool{}
+	ids := []uuid.UUID{}
+	for _, c := range chirps {
+		if !seen[c.UserID] {
+			seen[c.UserID] = true
+			ids = append(ids, c.UserID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	dbAuthors, err := cfg.dbQueries.GetAuthors(ctx, ids)
+	if err != nil {
+		return err
+	}
+	authors := map[uuid.UUID]*author{}
+	for _, dbAuthor := range dbAuthors {
+		authors[dbAuthor.ID] = &author{
+			ID:          dbAuthor.ID,
+			Handle:      dbAuthor.Handle.String,
+			DisplayName: dbAuthor.DisplayName,
+			AvatarURL:   avatarURL(dbAuthor.ID, dbAuthor.HasAvatar),
+		}
+	}
+	for i := range chirps {
+		chirps[i].Author = authors[chirps[i].UserID]
+	}
+	return nil
+}
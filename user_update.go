@@ -1,60 +1,241 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"database/sql"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/marekbrze/chirpy/internal/auth"
 	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/problem"
+	"github.com/marekbrze/chirpy/internal/validate"
 )
 
+// userUpdateData replaces the email and password. Like a patch that changes
+// them, it needs the current password.
 type userUpdateData struct {
-	NewEmail    string `json:"email"`
-	NewPassword string `json:"password"`
+	NewEmail        string `json:"email"`
+	NewPassword     string `json:"password"`
+	CurrentPassword string `json:"current_password"`
+}
+
+func (u userUpdateData) validate(v *validate.Validator) {
+	v.Check(validate.NotBlank(u.NewEmail), "email", "is required")
+	v.Check(validate.Email(u.NewEmail), "email", "must be an email address")
+	v.Check(validate.NotBlank(u.NewPassword), "password", "is required")
+	v.Check(validate.NotBlank(u.CurrentPassword), "current_password", "is required to change the email or password")
 }
 
 func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
-	headerToken, err := auth.GetBearerToken(r.Header)
+	userID, err := cfg.authenticate(r)
 	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+		respondWithError(w, r, err)
 		return
 	}
-	userID, err := auth.ValidateJWT(headerToken, cfg.jwtSecret)
+	receivedUserData := userUpdateData{}
+	err = decodeJSON(w, r, &receivedUserData)
 	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+		respondWithError(w, r, err)
 		return
 	}
-	decoder := json.NewDecoder(r.Body)
-	receivedUserData := userUpdateData{}
-	err = decoder.Decode(&receivedUserData)
+	hashedPassword, err := auth.HashPassword(receivedUserData.NewPassword)
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, r, err)
 		return
 	}
-	hashedPassword, err := auth.HashPassword(receivedUserData.NewPassword)
+	var user database.User
+	err = cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		previous, err := q.GetUserByID(r.Context(), userID)
+		if err == sql.ErrNoRows {
+			return errUnauthorized
+		}
+		if err != nil {
+			return err
+		}
+		passwordCorrect, err := auth.CheckPasswordHash(receivedUserData.CurrentPassword, previous.HashedPassword)
+		if err != nil || !passwordCorrect {
+			return errIncorrectPassword
+		}
+		user, err = q.UpdateUser(r.Context(), database.UpdateUserParams{
+			ID:             userID,
+			Email:          receivedUserData.NewEmail,
+			HashedPassword: hashedPassword,
+			UpdatedAt:      time.Now().UTC(),
+		})
+		if database.IsUniqueViolation(err) {
+			return errEmailTaken
+		}
+		if err != nil {
+			return err
+		}
+		return auditCredentialChanges(r.Context(), q, r, previous, user)
+	})
+	if err != nil {
+		respondWithError(w, r, err)
+		return
+	}
+	respondWithJSON(w, 200, userFromDB(user))
+}
+
+// auditCredentialChanges records whichever of the email and password changed
+// between previous and user.
+func auditCredentialChanges(ctx context.Context, q database.Querier, r *http.Request, previous, user database.User) error {
+	if user.Email != previous.Email {
+		err := audit(ctx, q, r, auditEmailChange, user.ID, user.ID, map[string]any{"previous_email": previous.Email, "email": user.Email})
+		if err != nil {
+			return err
+		}
+	}
+	if user.HashedPassword != previous.HashedPassword {
+		return audit(ctx, q, r, auditPasswordChange, user.ID, user.ID, nil)
+	}
+	return nil
+}
+
+// userPatch is a JSON Merge Patch (RFC 7396) of the user. Changing the email
+// or password needs the current password, so a stolen access token can't be
+// used to take the account over. Setting a profile field to null clears it.
+type userPatch struct {
+	Email           optional[string] `json:"email"`
+	Password        optional[string] `json:"password"`
+	CurrentPassword string           `json:"current_password"`
+	Handle          optional[string] `json:"handle"`
+	DisplayName     optional[string] `json:"display_name"`
+	Bio             optional[string] `json:"bio"`
+}
+
+func (p userPatch) validate(v *validate.Validator) {
+	if p.Email.Set {
+		v.Check(!p.Email.Null, "email", "can't be removed")
+		v.Check(validate.Email(p.Email.Value), "email", "must be an email address")
+	}
+	if p.Password.Set {
+		v.Check(!p.Password.Null, "password", "can't be removed")
+		v.Check(validate.NotBlank(p.Password.Value), "password", "is required")
+	}
+	if p.sensitive() {
+		v.Check(validate.NotBlank(p.CurrentPassword), "current_password", "is required to change the email or password")
+	}
+	if p.Handle.Set && !p.Handle.Null {
+		v.Check(handlePattern.MatchString(p.Handle.Value), "handle", "must be 3 to 30 letters, digits or underscores")
+	}
+	v.Check(validate.MaxLength(p.DisplayName.Value, 50), "display_name", "must be at most 50 characters")
+	v.Check(validate.MaxLength(p.Bio.Value, 160), "bio", "must be at most 160 characters")
+}
+
+func (p userPatch) sensitive() bool {
+	return p.Email.Set || p.Password.Set
+}
+
+func (p userPatch) profile() bool {
+	return p.Handle.Set || p.DisplayName.Set || p.Bio.Set
+}
+
+func (cfg *apiConfig) patchUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, r, err)
 		return
 	}
-	updateUserParams := database.UpdateUserParams{
-		ID:             userID,
-		Email:          receivedUserData.NewEmail,
-		HashedPassword: hashedPassword,
-		UpdatedAt:      time.Now().UTC(),
+	if !isMergePatch(r) {
+		respondWithError(w, r, problem.New(problem.UnsupportedMediaType, "Send the patch as application/merge-patch+json"))
+		return
 	}
-	user, err := cfg.dbQueries.UpdateUser(r.Context(), updateUserParams)
+	patch := userPatch{}
+	err = decodeJSON(w, r, &patch)
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		respondWithError(w, r, err)
 		return
 	}
-	responseUser := User{
-		ID:          user.ID,
-		Email:       user.Email,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		IsChirpyRed: user.IsChirpyRed,
+	newHash := ""
+	if patch.Password.Set {
+		newHash, err = auth.HashPassword(patch.Password.Value)
+		if err != nil {
+			respondWithError(w, r, err)
+			return
+		}
+	}
+	var user database.User
+	err = cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		var err error
+		user, err = q.GetUserByID(r.Context(), userID)
+		if err == sql.ErrNoRows {
+			return errUnauthorized
+		}
+		if err != nil {
+			return err
+		}
+		if patch.sensitive() {
+			passwordCorrect, err := auth.CheckPasswordHash(patch.CurrentPassword, user.HashedPassword)
+			if err != nil || !passwordCorrect {
+				return errIncorrectPassword
+			}
+			params := database.UpdateUserParams{
+				ID:             user.ID,
+				Email:          user.Email,
+				HashedPassword: user.HashedPassword,
+				UpdatedAt:      time.Now().UTC(),
+			}
+			if patch.Email.Set {
+				params.Email = patch.Email.Value
+			}
+			if patch.Password.Set {
+				params.HashedPassword = newHash
+			}
+			previous := user
+			user, err = q.UpdateUser(r.Context(), params)
+			if database.IsUniqueViolation(err) {
+				return errEmailTaken
+			}
+			if err != nil {
+				return err
+			}
+			if err := auditCredentialChanges(r.Context(), q, r, previous, user); err != nil {
+				return err
+			}
+		}
+		if patch.profile() {
+			params := database.UpdateUserProfileParams{
+				ID:          user.ID,
+				Handle:      user.Handle,
+				DisplayName: user.DisplayName,
+				Bio:         user.Bio,
+				UpdatedAt:   time.Now().UTC(),
+			}
+			if patch.Handle.Set {
+				params.Handle = sql.NullString{String: strings.ToLower(patch.Handle.Value), Valid: !patch.Handle.Null}
+			}
+			if patch.DisplayName.Set {
+				params.DisplayName = patch.DisplayName.Value
+			}
+			if patch.Bio.Set {
+				params.Bio = patch.Bio.Value
+			}
+			user, err = q.UpdateUserProfile(r.Context(), params)
+			if database.IsUniqueViolation(err) {
+				return errHandleTaken
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithError(w, r, err)
+		return
 	}
+	respondWithJSON(w, 200, userFromDB(user))
+}
 
-	respondWithJSON(w, 200, responseUser)
+// isMergePatch accepts plain JSON too, since most clients send that.
+func isMergePatch(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/merge-patch+json" || mediaType == "application/json"
 }
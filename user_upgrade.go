@@ -1,63 +1,78 @@
 package main
 
 import (
+	"crypto/subtle"
 	"database/sql"
-	"encoding/json"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/marekbrze/chirpy/internal/auth"
 	"github.com/marekbrze/chirpy/internal/database"
+	"github.com/marekbrze/chirpy/internal/validate"
 )
 
 type upgradeParams struct {
 	Event string `json:"event"`
 	Data  struct {
 		UserID uuid.UUID `json:"user_id"`
+	} `json:"data"`
+}
+
+func (p upgradeParams) validate(v *validate.Validator) {
+	v.Check(validate.NotBlank(p.Event), "event", "is required")
+	if p.Event == "user.upgraded" {
+		v.Check(p.Data.UserID != uuid.Nil, "data.user_id", "is required")
 	}
 }
 
 func (cfg *apiConfig) upgradeUser(w http.ResponseWriter, r *http.Request) {
 	headerToken, err := auth.GetAPIKey(r.Header)
 	if err != nil {
-		respondWithError(w, 401, "Unauthorized")
+		cfg.metrics.webhooks.WithLabelValues("unauthorized").Inc()
+		respondWithError(w, r, errUnauthorized)
 		return
 	}
-	if headerToken != cfg.apiKey {
-		respondWithError(w, 401, "Unauthorized")
+	if subtle.ConstantTimeCompare([]byte(headerToken), []byte(cfg.apiKey)) != 1 {
+		cfg.metrics.webhooks.WithLabelValues("unauthorized").Inc()
+		respondWithError(w, r, errUnauthorized)
 		return
 	}
-	decoder := json.NewDecoder(r.Body)
 	receivedParams := upgradeParams{}
-	err = decoder.Decode(&receivedParams)
+	err = decodeLenientJSON(w, r, &receivedParams)
 	if err != nil {
-		respondWithError(w, 500, "Something went wrong")
+		cfg.metrics.webhooks.WithLabelValues("error").Inc()
+		respondWithError(w, r, err)
 		return
 	}
 	if receivedParams.Event != "user.upgraded" {
+		cfg.metrics.webhooks.WithLabelValues("ignored").Inc()
 		respondWithJSON(w, 204, nil)
+		return
 	}
-	upgradeUserParams := database.UpgradeUserParams{
-		ID:          receivedParams.Data.UserID,
-		UpdatedAt:   time.Now().UTC(),
-		IsChirpyRed: true,
-	}
-	user, err := cfg.dbQueries.UpgradeUser(r.Context(), upgradeUserParams)
+	var user database.User
+	err = cfg.tx.InTx(r.Context(), func(q database.Querier) error {
+		var err error
+		user, err = q.UpgradeUser(r.Context(), database.UpgradeUserParams{
+			ID:          receivedParams.Data.UserID,
+			UpdatedAt:   time.Now().UTC(),
+			IsChirpyRed: true,
+		})
+		if err != nil {
+			return err
+		}
+		return audit(r.Context(), q, r, auditChirpyRedUpgrade, uuid.Nil, user.ID, nil)
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
-			respondWithError(w, 404, "User doesn't exist")
+			cfg.metrics.webhooks.WithLabelValues("not_found").Inc()
+			respondWithError(w, r, errUserNotFound)
 			return
 		}
-		respondWithError(w, 500, "Something went wrong")
+		cfg.metrics.webhooks.WithLabelValues("error").Inc()
+		respondWithError(w, r, err)
 		return
 	}
-	responseUser := User{
-		ID:          user.ID,
-		Email:       user.Email,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		IsChirpyRed: user.IsChirpyRed,
-	}
-	respondWithJSON(w, 204, responseUser)
+	cfg.metrics.webhooks.WithLabelValues("upgraded").Inc()
+	respondWithJSON(w, 204, userFromDB(user))
 }